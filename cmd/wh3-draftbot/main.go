@@ -1,9 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/config"
 	"github.com/tmwilder/wh3-draftbot/internal/web/app"
+	"os"
 )
 
 func main() {
-	app.App()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not load config: "+err.Error())
+		os.Exit(2)
+	}
+	app.App(cfg)
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"github.com/tmwilder/wh3-draftbot/internal/config"
+	"os"
+)
+
+/**
+Builds an opening book for a format and set of matchup odds and writes it out as JSON, for the server to load from its
+book directory.
+*/
+func main() {
+	rounds := flag.Int("rounds", 5, "games in the series")
+	rulesetName := flag.String("ruleset", Turin2022Q2.Name, "ruleset to draft under")
+	matchupOddsFile := flag.String("matchup-odds", "", "JSON file of matchup odds, MatchupsV1d2 where it leaves them out")
+	depth := flag.Int("depth", 1, "draft steps to solve every position of")
+	out := flag.String("out", "", "file to write the book to")
+	flag.Parse()
+
+	if err := build(*rounds, *rulesetName, *matchupOddsFile, *depth, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not build opening book: "+err.Error())
+		os.Exit(1)
+	}
+}
+
+func build(rounds int, rulesetName string, matchupOddsFile string, depth int, out string) error {
+	if out == "" {
+		return fmt.Errorf("no -out file given")
+	}
+	ruleset, ok := Rulesets[rulesetName]
+	if !ok {
+		return fmt.Errorf("unknown ruleset: %s", rulesetName)
+	}
+	if ruleset.TeamSize > 1 {
+		return fmt.Errorf("team drafts don't have opening books")
+	}
+	matchupOdds, err := config.Config{MatchupOddsFile: matchupOddsFile}.MatchupOdds()
+	if err != nil {
+		return err
+	}
+	tournamentInfo := TournamentInfo{RoundCount: rounds, MatchupOdds: matchupOdds, Ruleset: ruleset}
+	if err := tournamentInfo.Validate(); err != nil {
+		return err
+	}
+
+	book := algo.BuildOpeningBook(tournamentInfo, depth, func(solved int, total int) {
+		fmt.Printf("Solved %d of %d positions\n", solved, total)
+	})
+	data, err := json.Marshal(book)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, data, 0644)
+}
@@ -2,9 +2,10 @@ module github.com/tmwilder/wh3-draftbot
 
 go 1.18
 
+require github.com/gin-gonic/gin v1.8.0
+
 require (
 	github.com/gin-contrib/sse v0.1.0 // indirect
-	github.com/gin-gonic/gin v1.8.0 // indirect
 	github.com/go-playground/locales v0.14.0 // indirect
 	github.com/go-playground/universal-translator v0.18.0 // indirect
 	github.com/go-playground/validator/v10 v10.11.0 // indirect
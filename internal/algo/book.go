@@ -0,0 +1,118 @@
+package algo
+
+import (
+	"encoding/json"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"reflect"
+)
+
+// BookEntry is a solved position: P1's series win rate with perfect play from there on, the line that gets it and the
+// odds of each final score.
+type BookEntry struct {
+	Value     float64
+	Line      GameState
+	ScoreOdds []ScoreOdds
+}
+
+/**
+OpeningBook holds every position of the first Depth steps of the draft solved ahead of time, for one format and one
+set of matchup odds. The searches from near the start of a long series are the slowest there are, so a book built
+offline saves doing them again for every request.
+
+A book only answers for the format, player pools and odds it was built with. Once the odds change, their hash no
+longer matches and the book is ignored until it is rebuilt.
+*/
+type OpeningBook struct {
+	RoundCount int
+	Ruleset    Ruleset
+	// P1Pool and P2Pool are what each player could draft, sorted, with an empty pool filled in from the ruleset.
+	P1Pool   []Faction
+	P2Pool   []Faction
+	OddsHash string
+	Depth    int
+	// Positions are keyed by PositionKey.
+	Positions map[string]BookEntry
+}
+
+/**
+Solves every position up to depth steps into the draft. Game results count as steps where the draft waits on them.
+progress, if set, is told how many of the positions have been solved so far after each one.
+*/
+func BuildOpeningBook(tournamentInfo TournamentInfo, depth int, progress func(solved int, total int)) OpeningBook {
+	book := OpeningBook{
+		RoundCount: tournamentInfo.RoundCount,
+		Ruleset:    tournamentInfo.Ruleset.Canonical(),
+		P1Pool:     tournamentInfo.PlayerPool(true),
+		P2Pool:     tournamentInfo.PlayerPool(false),
+		OddsHash:   OddsHash(tournamentInfo.MatchupOdds),
+		Depth:      depth,
+		Positions:  map[string]BookEntry{},
+	}
+
+	var positions []GameState
+	seen := map[string]bool{}
+	frontier := []GameState{{}}
+	for step := 0; step <= depth; step++ {
+		var next []GameState
+		for _, v := range frontier {
+			key := PositionKey(v)
+			if seen[key] || draftIsComplete(tournamentInfo, v) {
+				continue
+			}
+			seen[key] = true
+			positions = append(positions, v)
+			if step < depth {
+				next = append(next, getSuccessors(tournamentInfo, v)...)
+			}
+		}
+		frontier = next
+	}
+
+	for i, v := range positions {
+		value, line := TurinMinimax(tournamentInfo, v, IsP1PickNext(tournamentInfo, v), -1.0, 2.0)
+		var scoreOdds []ScoreOdds
+		if tournamentInfo.Ruleset.DependsOnResults() {
+			scoreOdds = SolveScoreDistribution(tournamentInfo, v, nil)
+		} else {
+			scoreOdds = ScoreDistribution(tournamentInfo, line)
+		}
+		book.Positions[PositionKey(v)] = BookEntry{Value: value, Line: line, ScoreOdds: scoreOdds}
+		if progress != nil {
+			progress(i+1, len(positions))
+		}
+	}
+	return book
+}
+
+/**
+Whether the book was built for tournamentInfo's format, player pools and matchup odds.
+*/
+func (b OpeningBook) Matches(tournamentInfo TournamentInfo) bool {
+	return b.RoundCount == tournamentInfo.RoundCount &&
+		b.OddsHash == OddsHash(tournamentInfo.MatchupOdds) &&
+		reflect.DeepEqual(b.Ruleset, tournamentInfo.Ruleset.Canonical()) &&
+		reflect.DeepEqual(b.P1Pool, tournamentInfo.PlayerPool(true)) &&
+		reflect.DeepEqual(b.P2Pool, tournamentInfo.PlayerPool(false))
+}
+
+/**
+The solved position for gameState, if the book has it and was built for tournamentInfo.
+*/
+func (b OpeningBook) Lookup(tournamentInfo TournamentInfo, gameState GameState) (BookEntry, bool) {
+	if !b.Matches(tournamentInfo) {
+		return BookEntry{}, false
+	}
+	entry, ok := b.Positions[PositionKey(gameState)]
+	return entry, ok
+}
+
+/**
+Identifies a position however it was written down.
+*/
+func PositionKey(gameState GameState) string {
+	key, err := json.Marshal(CanonicalGameState(gameState))
+	if err != nil {
+		panic("Cannot encode game state: " + err.Error())
+	}
+	return string(key)
+}
@@ -0,0 +1,91 @@
+package algo
+
+import (
+	"encoding/json"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestOpeningBook(t *testing.T) {
+	tournamentInfo := TournamentInfo{
+		RoundCount:  3,
+		MatchupOdds: MatchupsV1d2,
+		Ruleset:     Ruleset{FactionPool: []Faction{GC, KH, KI, NG}, Repeats: AllowRepeats},
+	}
+	book := BuildOpeningBook(tournamentInfo, 1, nil)
+
+	// The empty draft and every first pick.
+	if expected := 1 + len(getSuccessors(tournamentInfo, GameState{})); len(book.Positions) != expected {
+		t.Errorf("Expected %d positions but got %d", expected, len(book.Positions))
+	}
+	for _, v := range append(getSuccessors(tournamentInfo, GameState{}), GameState{}) {
+		entry, ok := book.Lookup(tournamentInfo, v)
+		value, _ := TurinMinimax(tournamentInfo, v, IsP1PickNext(tournamentInfo, v), -1.0, 2.0)
+		if !ok || !(math.Abs(entry.Value-value) < epsilon) {
+			t.Errorf("Expected the book to have %+v worth %f but got %+v", v, value, entry)
+		}
+	}
+
+	// Books are read back from disk, and positions written differently are the same position.
+	data, _ := json.Marshal(book)
+	var loaded OpeningBook
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Expected the book to load but got %s", err)
+	}
+	entry, ok := loaded.Lookup(tournamentInfo, GameState{P2Rounds: []P2Round{}, P1Bans: []Faction{}})
+	if !ok || !reflect.DeepEqual(entry.Line, book.Positions[PositionKey(GameState{})].Line) {
+		t.Errorf("Expected the loaded book to have the empty draft but got %+v", entry)
+	}
+	if value, _ := (MinimaxSolver{Book: &loaded}).Solve(tournamentInfo, GameState{}, true); value != entry.Value {
+		t.Errorf("Expected the solver to take %f from the book but got %f", entry.Value, value)
+	}
+
+	// New odds invalidate the book.
+	changed := tournamentInfo
+	changed.MatchupOdds = map[Matchup]float64{Matchup{P1: GC, P2: KH}: .9}
+	if _, ok := loaded.Lookup(changed, GameState{}); ok {
+		t.Errorf("Expected a book for other odds to be ignored")
+	}
+	longer := tournamentInfo
+	longer.RoundCount = 5
+	if _, ok := loaded.Lookup(longer, GameState{}); ok {
+		t.Errorf("Expected a book for another format to be ignored")
+	}
+	narrowed := tournamentInfo
+	narrowed.P2Pool = []Faction{GC, KH, KI}
+	if _, ok := loaded.Lookup(narrowed, GameState{}); ok {
+		t.Errorf("Expected a book for other player pools to be ignored")
+	}
+	// A pool that is the whole ruleset's pool is the same as none at all.
+	widened := tournamentInfo
+	widened.P1Pool = []Faction{NG, KI, KH, GC}
+	if _, ok := loaded.Lookup(widened, GameState{}); !ok {
+		t.Errorf("Expected a book to answer for a player pool that is the whole pool")
+	}
+}
+
+func TestPositionKeyIgnoresPickOrder(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{{Picks: []Faction{KH, GC}, Matchup: Matchup{P1: GC, P2: SL}}},
+		P3Round:  P3Round{Picks: []Faction{TZ, KI, OK}},
+		P1Bans:   []Faction{NG, KH},
+	}
+	reordered := GameState{
+		P2Rounds: []P2Round{{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: SL}}},
+		P3Round:  P3Round{Picks: []Faction{KI, OK, TZ}},
+		P1Bans:   []Faction{NG, KH},
+	}
+	if PositionKey(gameState) != PositionKey(reordered) {
+		t.Errorf("Expected %s and %s to be the same position", PositionKey(gameState), PositionKey(reordered))
+	}
+	if gameState.P2Rounds[0].Picks[0] != KH || gameState.P3Round.Picks[0] != TZ {
+		t.Errorf("Expected the game state's own picks to keep their order but got %+v", gameState)
+	}
+
+	reordered.P1Bans = []Faction{KH, NG}
+	if PositionKey(gameState) == PositionKey(reordered) {
+		t.Errorf("Expected bans in another order to be another position")
+	}
+}
@@ -0,0 +1,98 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+)
+
+// Evaluator scores a draft that may still have picks left to make, as P1's estimated series win rate.
+type Evaluator func(tournamentInfo TournamentInfo, gameState GameState) float64
+
+/**
+Estimates P1's series win rate without searching.
+
+Every game whose result is recorded counts as won or lost, and every game whose matchup is already set uses its real
+odds. For the rest, each player is assumed to bring any faction they could still legally play in that game with equal
+likelihood, so the game is scored as the average matchup between the two players' remaining options. The per game
+estimates are then combined the same way computeWinRate combines real odds. Complete drafts get their exact win rate.
+*/
+func PoolEvaluator(tournamentInfo TournamentInfo, gameState GameState) float64 {
+	if draftIsComplete(tournamentInfo, gameState) {
+		return computeWinRate(tournamentInfo, gameState)
+	}
+
+	p1Pool := getRemainingPicks(tournamentInfo, gameState, true)
+	p2Pool := getRemainingPicks(tournamentInfo, gameState, false)
+
+	var gameOdds []float64
+	for i, round := range gameState.P2Rounds {
+		if round.WhoWon != NoOneYet {
+			gameOdds = append(gameOdds, roundOdds(tournamentInfo, round))
+			continue
+		}
+		isP1First := isP1FirstInGame(tournamentInfo, gameState, i)
+		var p1Options, p2Options []Faction
+		if isP1First {
+			p1Options = getPickOptions(round.Matchup.P1, round.Picks, EMPTY, p1Pool)
+			p2Options = getPickOptions(round.Matchup.P2, nil, EMPTY, p2Pool)
+		} else {
+			p1Options = getPickOptions(round.Matchup.P1, nil, EMPTY, p1Pool)
+			p2Options = getPickOptions(round.Matchup.P2, round.Picks, EMPTY, p2Pool)
+		}
+		gameOdds = append(gameOdds, averageMatchupValue(tournamentInfo, p1Options, p2Options, round.Matchup.Map))
+	}
+
+	for i := len(gameState.P2Rounds); i < tournamentInfo.RoundCount-1; i++ {
+		gameOdds = append(gameOdds, averageMatchupValue(tournamentInfo, p1Pool, p2Pool, ""))
+	}
+
+	finalRound := gameState.P3Round
+	isP1First := isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds))
+	var p1Options, p2Options []Faction
+	if isP1First {
+		p1Options = getPickOptions(finalRound.Matchup.P1, finalRound.Picks, finalRound.CounterBan, p1Pool)
+		p2Options = getPickOptions(finalRound.Matchup.P2, nil, finalRound.Ban, p2Pool)
+	} else {
+		p1Options = getPickOptions(finalRound.Matchup.P1, nil, finalRound.Ban, p1Pool)
+		p2Options = getPickOptions(finalRound.Matchup.P2, finalRound.Picks, finalRound.CounterBan, p2Pool)
+	}
+	gameOdds = append(gameOdds, averageMatchupValue(tournamentInfo, p1Options, p2Options, finalRound.Matchup.Map))
+
+	return seriesWinRate(gameOdds)
+}
+
+/**
+The factions a player might still end up playing in one game: the one they played if it is already known, otherwise
+whichever of their initial picks survived, otherwise anything left in their pool. banned is never an option.
+*/
+func getPickOptions(played Faction, picks []Faction, banned Faction, pool []Faction) []Faction {
+	if played != EMPTY {
+		return []Faction{played}
+	}
+	candidates := pool
+	if len(picks) > 0 {
+		candidates = picks
+	}
+	var options []Faction
+	for _, v := range candidates {
+		if v != banned {
+			options = append(options, v)
+		}
+	}
+	return options
+}
+
+/**
+gameMap is blank for games whose map isn't known yet, which scores them with the map agnostic odds.
+*/
+func averageMatchupValue(tournamentInfo TournamentInfo, p1Options []Faction, p2Options []Faction, gameMap GameMap) float64 {
+	if len(p1Options) == 0 || len(p2Options) == 0 {
+		return .5
+	}
+	total := 0.0
+	for _, p1 := range p1Options {
+		for _, p2 := range p2Options {
+			total += GetMatchupValue(Matchup{P1: p1, P2: p2, Map: gameMap}, tournamentInfo)
+		}
+	}
+	return total / float64(len(p1Options)*len(p2Options))
+}
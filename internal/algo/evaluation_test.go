@@ -0,0 +1,167 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+	"time"
+)
+
+// How far the pool heuristic may stray from the exact Bo3 values below.
+const heuristicTolerance = .05
+
+var bo3Positions = []GameState{
+	{
+		P2Rounds: []P2Round{},
+		P3Round:  P3Round{},
+	},
+	{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+		},
+		P3Round: P3Round{},
+	},
+	{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+		},
+		P3Round: P3Round{},
+	},
+	{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{NG, TZ}, Matchup: Matchup{P1: NG, P2: KI}},
+			{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: KI}},
+		},
+		P3Round: P3Round{},
+	},
+}
+
+func TestPoolEvaluatorExactOnCompleteDraft(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+		},
+		P3Round: P3Round{
+			Picks:      []Faction{GC, KI, NG},
+			Ban:        SL,
+			CounterBan: NG,
+			Matchup:    Matchup{P1: KI, P2: TZ}},
+	}
+
+	expected := computeWinRate(tournamentInfo, gameState)
+	actual := PoolEvaluator(tournamentInfo, gameState)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, actual)
+	}
+}
+
+func TestPoolEvaluatorCloseToExactBo3(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+
+	for _, gameState := range bo3Positions {
+		exact, _ := TurinMinimax(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), -1.0, 2.0)
+		estimate := PoolEvaluator(tournamentInfo, gameState)
+		if !(math.Abs(exact-estimate) < heuristicTolerance) {
+			t.Errorf("Expected estimate for %+v to be within %f of %f but it was %f", gameState, heuristicTolerance, exact, estimate)
+		}
+	}
+}
+
+func TestPoolEvaluatorPolarizedFinalRound(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: OK}},
+			{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: KH}},
+		},
+		P3Round: P3Round{
+			Picks:      []Faction{KI, NG, OK},
+			Ban:        KI,
+			CounterBan: KI,
+			Matchup:    Matchup{P1: EMPTY, P2: GC}},
+	}
+
+	// P1 won game one outright and splits game two. Their final pick is NG or OK against GC, both certain losses.
+	expected := .5
+	actual := PoolEvaluator(tournamentInfo, gameState)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, actual)
+	}
+}
+
+func TestDepthLimitedMatchesExactWithEnoughDepth(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+
+	for _, gameState := range bo3Positions[1:] {
+		isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
+		exact, exactGameState := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+		limited, limitedGameState := TurinMinimaxDepthLimited(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0, 20, PoolEvaluator)
+		if !(math.Abs(exact-limited) < epsilon) {
+			t.Errorf("Expected WR to be %f but it was %f", exact, limited)
+		}
+		if !draftIsComplete(tournamentInfo, limitedGameState) {
+			t.Errorf("Expected a complete line like %+v but got %+v", exactGameState, limitedGameState)
+		}
+	}
+}
+
+func TestDepthLimitedZeroDepthUsesEvaluator(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := bo3Positions[2]
+
+	expected := PoolEvaluator(tournamentInfo, gameState)
+	actual, actualGameState := TurinMinimaxDepthLimited(tournamentInfo, gameState, true, -1.0, 2.0, 0, PoolEvaluator)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, actual)
+	}
+	if len(actualGameState.P3Round.Picks) != 0 {
+		t.Errorf("Expected no picks to be made but got %+v", actualGameState)
+	}
+}
+
+func TestDepthLimitedShallowSearchBo5(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{},
+		P3Round:  P3Round{},
+	}
+
+	value, lineGameState := TurinMinimaxDepthLimited(tournamentInfo, gameState, true, -1.0, 2.0, 3, PoolEvaluator)
+	if value < 0.0 || value > 1.0 {
+		t.Errorf("Expected WR to be a probability but it was %f", value)
+	}
+	if len(lineGameState.P2Rounds) != 1 || lineGameState.P2Rounds[0].Matchup.P1 == EMPTY {
+		t.Errorf("Expected the line to cover exactly the first round but got %+v", lineGameState)
+	}
+}
+
+func TestTimedSolvesSmallPositionExactly(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := bo3Positions[2]
+
+	exact, _ := TurinMinimax(tournamentInfo, gameState, true, -1.0, 2.0)
+	timed, timedGameState := TurinMinimaxTimed(tournamentInfo, gameState, true, time.Minute, PoolEvaluator)
+	if !(math.Abs(exact-timed) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", exact, timed)
+	}
+	if !draftIsComplete(tournamentInfo, timedGameState) {
+		t.Errorf("Expected a complete line but got %+v", timedGameState)
+	}
+}
+
+func TestTimedFallsBackToEvaluator(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{},
+		P3Round:  P3Round{},
+	}
+
+	expected := PoolEvaluator(tournamentInfo, gameState)
+	actual, _ := TurinMinimaxTimed(tournamentInfo, gameState, true, 0, PoolEvaluator)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, actual)
+	}
+}
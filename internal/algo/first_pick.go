@@ -0,0 +1,90 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+)
+
+/**
+Whether P1 makes the initial picks in the zero based game, going by the ruleset's FirstPick rule. Every game before it
+must have been drafted. A result that hasn't been recorded counts as a P1 win.
+*/
+func isP1FirstInGame(tournamentInfo TournamentInfo, gameState GameState, game int) bool {
+	if game == 0 {
+		return true
+	}
+	p1WonPrevious := gameState.P2Rounds[game-1].WhoWon != P2
+	switch tournamentInfo.Ruleset.FirstPick {
+	case LoserPicksFirst:
+		return !p1WonPrevious
+	case WinnerPicksFirst:
+		return p1WonPrevious
+	default:
+		if game == tournamentInfo.RoundCount-1 {
+			return p1WonPrevious
+		}
+		return game%2 == 0
+	}
+}
+
+/**
+Whether the draft is waiting on the result of the game just drafted. Results only hold the draft up under rulesets that
+depend on them. Otherwise the winner of the game before the final one is taken as recorded, or as P1 if it isn't.
+*/
+func resultIsNext(tournamentInfo TournamentInfo, gameState GameState) bool {
+	if !tournamentInfo.Ruleset.DependsOnResults() || len(gameState.P2Rounds) == 0 {
+		return false
+	}
+	lastRound := gameState.P2Rounds[len(gameState.P2Rounds)-1]
+	return lastRound.Matchup.P1 != EMPTY && lastRound.Matchup.P2 != EMPTY && lastRound.WhoWon == NoOneYet
+}
+
+/**
+The game just drafted won by P1, then by P2.
+*/
+func getSuccessorsResult(previousGameState GameState) []GameState {
+	var successors []GameState
+	for _, v := range []WhoWon{P1, P2} {
+		newGameState := deepcopy(previousGameState)
+		newGameState.P2Rounds[len(newGameState.P2Rounds)-1].WhoWon = v
+		successors = append(successors, newGameState)
+	}
+	return successors
+}
+
+/**
+P1's odds of winning the game whose result is next.
+*/
+func resultOdds(tournamentInfo TournamentInfo, gameState GameState) float64 {
+	return GetMatchupValue(gameState.P2Rounds[len(gameState.P2Rounds)-1].Matchup, tournamentInfo)
+}
+
+/**
+Whether player won a game of the series with faction, which locks them out of it under WinnerLockedOut.
+*/
+func wonWith(gameState GameState, isP1 bool, faction Faction) bool {
+	for _, v := range gameState.P2Rounds {
+		if isP1 && v.WhoWon == P1 && v.Matchup.P1 == faction {
+			return true
+		}
+		if !isP1 && v.WhoWon == P2 && v.Matchup.P2 == faction {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Averages value over both results of the game whose result is next, weighted by P1's odds in the game, and returns the
+line that follows the likelier result.
+*/
+func expectOverResult(tournamentInfo TournamentInfo, gameState GameState, value func(GameState) (float64, GameState)) (float64, GameState) {
+	p1Odds := resultOdds(tournamentInfo, gameState)
+	successors := getSuccessorsResult(gameState)
+	p1WinValue, p1WinLine := value(successors[0])
+	p2WinValue, p2WinLine := value(successors[1])
+	line := p1WinLine
+	if p1Odds < .5 {
+		line = p2WinLine
+	}
+	return p1Odds*p1WinValue + (1.0-p1Odds)*p2WinValue, line
+}
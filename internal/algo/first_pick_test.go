@@ -0,0 +1,89 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFirstPickRules(t *testing.T) {
+	// P1 wins the first game and P2 the second and third.
+	gameState := GameState{P2Rounds: []P2Round{
+		{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: NG}, WhoWon: P1},
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: GC, P2: SL}, WhoWon: P2},
+	}}
+	expected := map[FirstPickRule][]bool{
+		AlternateFirstPick: {true, false, true, false},
+		LoserPicksFirst:    {true, false, true, true},
+		WinnerPicksFirst:   {true, true, false, false},
+	}
+	for rule, isP1First := range expected {
+		tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{FirstPick: rule}}
+		gameState := deepcopy(gameState)
+		gameState.P2Rounds = append(gameState.P2Rounds, P2Round{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: OK, P2: GC}, WhoWon: P2})
+		var actual []bool
+		for i := range isP1First {
+			actual = append(actual, isP1FirstInGame(tournamentInfo, gameState, i))
+		}
+		if !reflect.DeepEqual(isP1First, actual) {
+			t.Errorf("Expected %s to have P1 pick first %v but got %v", rule, isP1First, actual)
+		}
+	}
+
+	// The next game waits on the second game's result, and then goes to whoever lost it.
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{FirstPick: LoserPicksFirst}}
+	gameState.P2Rounds[1].WhoWon = NoOneYet
+	if NextStep(tournamentInfo, gameState) != GameResult || IsP1PickNext(tournamentInfo, gameState) {
+		t.Errorf("Expected the draft to wait on a result but got %s", NextStep(tournamentInfo, gameState))
+	}
+	for _, v := range getSuccessors(tournamentInfo, gameState) {
+		isP1Next := v.P2Rounds[1].WhoWon == P2
+		if NextStep(tournamentInfo, v) != InitialPicks || IsP1PickNext(tournamentInfo, v) != isP1Next {
+			t.Errorf("Expected the loser of %+v to pick first next", v.P2Rounds[1])
+		}
+	}
+}
+
+func TestWinnerLockedOut(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2LoserPicks}
+	gameState := GameState{P2Rounds: []P2Round{
+		{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: NG}, WhoWon: P1},
+	}}
+
+	if actual := getRemainingPicks(tournamentInfo, gameState, true); !reflect.DeepEqual(actual, []Faction{GC, KI, NG, OK, SL, TZ}) {
+		t.Errorf("Expected P1 to be locked out of KH but got %v", actual)
+	}
+	// The loser can play anything again.
+	if actual := getRemainingPicks(tournamentInfo, gameState, false); !reflect.DeepEqual(actual, tournamentInfo.Ruleset.Pool()) {
+		t.Errorf("Expected P2 to keep the whole pool but got %v", actual)
+	}
+}
+
+func TestMinimaxAveragesOverResults(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2LoserPicks}
+	gameState := GameState{P2Rounds: []P2Round{
+		{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: NG}, WhoWon: P1},
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: GC, P2: SL}},
+	}}
+
+	p1Odds := GetMatchupValue(Matchup{P1: GC, P2: SL}, tournamentInfo)
+	expected := 0.0
+	for _, v := range getSuccessorsResult(gameState) {
+		value, _ := TurinMinimax(tournamentInfo, v, IsP1PickNext(tournamentInfo, v), -1.0, 2.0)
+		if v.P2Rounds[1].WhoWon == P1 {
+			expected += p1Odds * value
+		} else {
+			expected += (1.0 - p1Odds) * value
+		}
+	}
+
+	actual, line := TurinMinimax(tournamentInfo, gameState, false, -1.0, 2.0)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected the result to be worth %f on average but got %f", expected, actual)
+	}
+	// GC is favoured over SL, so the line follows P1 winning.
+	if !draftIsComplete(tournamentInfo, line) || line.P2Rounds[1].WhoWon != P1 {
+		t.Errorf("Expected a complete line where P1 wins the second game but got %+v", line)
+	}
+}
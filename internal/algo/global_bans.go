@@ -0,0 +1,47 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+)
+
+/**
+Whether the series still has global bans to make. They come before anything else, map bans included.
+*/
+func globalBansRemain(tournamentInfo TournamentInfo, gameState GameState) bool {
+	return len(gameState.P1Bans)+len(gameState.P2Bans) < 2*tournamentInfo.Ruleset.GlobalBansPerPlayer
+}
+
+/**
+Global bans alternate starting with P1.
+*/
+func isP1GlobalBanNext(gameState GameState) bool {
+	return len(gameState.P1Bans) == len(gameState.P2Bans)
+}
+
+func getSuccessorsGlobalBan(tournamentInfo TournamentInfo, previousGameState GameState) []GameState {
+	var successors []GameState
+	isP1 := isP1GlobalBanNext(previousGameState)
+	// Bans are made against the opponent, so come out of what they could otherwise play.
+	for _, v := range getRemainingPicks(tournamentInfo, previousGameState, !isP1) {
+		newGameState := deepcopy(previousGameState)
+		if isP1 {
+			newGameState.P1Bans = append(newGameState.P1Bans, v)
+		} else {
+			newGameState.P2Bans = append(newGameState.P2Bans, v)
+		}
+		successors = append(successors, newGameState)
+	}
+	return successors
+}
+
+/**
+Whether a global ban rules faction out. Banning a race bans all of its lords.
+*/
+func isGloballyBanned(bans []Faction, faction Faction) bool {
+	for _, v := range bans {
+		if v == faction || v == Race(faction) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGlobalBanStepOrder(t *testing.T) {
+	ruleset := Ruleset{GlobalBansPerPlayer: 2, MapPool: []GameMap{"A", "B", "C"}, MapBansPerPlayer: 1}
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: ruleset}
+
+	type move struct {
+		step DraftStep
+		isP1 bool
+	}
+	expected := []move{
+		{GlobalBan, true}, {GlobalBan, false}, {GlobalBan, true}, {GlobalBan, false},
+		{MapBan, true}, {MapBan, false}, {MapPick, true}, {InitialPicks, true},
+	}
+	gameState := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}
+	var actual []move
+	for range expected {
+		actual = append(actual, move{NextStep(tournamentInfo, gameState), IsP1PickNext(tournamentInfo, gameState)})
+		gameState = getSuccessors(tournamentInfo, gameState)[0]
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the draft to open %v but got %v", expected, actual)
+	}
+	// Each ban comes out of the opponent's pool, so both players can ban the same faction.
+	if !reflect.DeepEqual(gameState.P1Bans, []Faction{GC, KH}) || !reflect.DeepEqual(gameState.P2Bans, []Faction{GC, KH}) {
+		t.Errorf("Expected both players to ban GC then KH but got %v and %v", gameState.P1Bans, gameState.P2Bans)
+	}
+}
+
+func TestRemainingPicksAfterGlobalBans(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{FactionPool: []Faction{GC, KI, Katarin, Kostaltyn, NG}}}
+	gameState := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}, P1Bans: []Faction{KI}, P2Bans: []Faction{Katarin, NG}}
+
+	// Banning a race bans its lords too, but banning a lord leaves the race and its other lords.
+	if actual := getRemainingPicks(tournamentInfo, gameState, false); !reflect.DeepEqual(actual, []Faction{GC, NG}) {
+		t.Errorf("Expected P2 to be left with [GC NG] but got %v", actual)
+	}
+	if actual := getRemainingPicks(tournamentInfo, gameState, true); !reflect.DeepEqual(actual, []Faction{GC, KI, Kostaltyn}) {
+		t.Errorf("Expected P1 to be left with [GC KI KI:KOS] but got %v", actual)
+	}
+}
+
+func TestMinimaxSearchesGlobalBans(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{GlobalBansPerPlayer: 1}}
+	// Both games before the last are already played, which keeps the search small.
+	gameState := deepcopy(bo3Positions[2])
+
+	expected := -1.0
+	for _, p1Ban := range getRemainingPicks(tournamentInfo, gameState, false) {
+		worst := 2.0
+		for _, p2Ban := range getRemainingPicks(tournamentInfo, gameState, true) {
+			banned := deepcopy(gameState)
+			banned.P1Bans = []Faction{p1Ban}
+			banned.P2Bans = []Faction{p2Ban}
+			value, _ := TurinMinimax(tournamentInfo, banned, IsP1PickNext(tournamentInfo, banned), -1.0, 2.0)
+			worst = math.Min(worst, value)
+		}
+		expected = math.Max(expected, worst)
+	}
+
+	actual, line := TurinMinimax(tournamentInfo, gameState, true, -1.0, 2.0)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected the bans to be worth %f but got %f", expected, actual)
+	}
+	if len(line.P1Bans) != 1 || len(line.P2Bans) != 1 || !draftIsComplete(tournamentInfo, line) {
+		t.Errorf("Expected the line to ban once each and finish the draft but got %+v", line)
+	}
+}
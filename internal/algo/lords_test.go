@@ -0,0 +1,101 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGetMatchupValueFallsBackToRace(t *testing.T) {
+	tournamentInfo := TournamentInfo{
+		MatchupOdds: map[Matchup]float64{
+			{P1: KI, P2: KH}:                          .4,
+			{P1: Katarin, P2: KH}:                     .7,
+			{P1: KI, P2: KH, Map: "Fortress"}:         .2,
+			{P1: Katarin, P2: Skarbrand, Map: "Pass"}: .9,
+		},
+	}
+	cases := []struct {
+		matchup  Matchup
+		expected float64
+	}{
+		{Matchup{P1: Katarin, P2: KH}, .7},
+		{Matchup{P1: KH, P2: Katarin}, .3},
+		{Matchup{P1: Katarin, P2: Skarbrand}, .7},
+		{Matchup{P1: Kostaltyn, P2: Skarbrand}, .4},
+		{Matchup{P1: Skarbrand, P2: Kostaltyn}, .6},
+		// Map odds beat lord odds, since a map can change a matchup more than the lord leading it.
+		{Matchup{P1: Katarin, P2: KH, Map: "Fortress"}, .2},
+		{Matchup{P1: Katarin, P2: Skarbrand, Map: "Pass"}, .9},
+		{Matchup{P1: Kostaltyn, P2: Skarbrand, Map: "Pass"}, .4},
+	}
+	for _, v := range cases {
+		actual := GetMatchupValue(v.matchup, tournamentInfo)
+		if !(math.Abs(actual-v.expected) < epsilon) {
+			t.Errorf("Expected %+v to be worth %f but got %f", v.matchup, v.expected, actual)
+		}
+	}
+}
+
+func TestRemainingPicksByRepeatGranularity(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{{Picks: []Faction{Katarin, Greasus}, Matchup: Matchup{P1: Katarin, P2: Skrag}}},
+		P3Round:  P3Round{},
+	}
+	cases := []struct {
+		repeats  RepeatGranularity
+		isP1     bool
+		expected []Faction
+	}{
+		{RaceRepeats, true, []Faction{MiaoYing, ZhaoMing, Skarbrand, Kugath, Greasus, Skrag, NKari, Kairos}},
+		{LordRepeats, true, []Faction{MiaoYing, ZhaoMing, Skarbrand, Kostaltyn, Kugath, Greasus, Skrag, NKari, Kairos}},
+		{RaceRepeats, false, []Faction{MiaoYing, ZhaoMing, Skarbrand, Katarin, Kostaltyn, Kugath, NKari, Kairos}},
+		{LordRepeats, false, []Faction{MiaoYing, ZhaoMing, Skarbrand, Katarin, Kostaltyn, Kugath, Greasus, NKari, Kairos}},
+	}
+	for _, v := range cases {
+		tournamentInfo := TournamentInfo{RoundCount: 3, Ruleset: Ruleset{FactionPool: AllLords(), Repeats: v.repeats}}
+		actual := getRemainingPicks(tournamentInfo, gameState, v.isP1)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf("Expected %s repeats to leave P1 (%t) with %v but got %v", v.repeats, v.isP1, v.expected, actual)
+		}
+	}
+}
+
+func TestLordOddsSteerTheDraft(t *testing.T) {
+	// The Kislev and Ogre lords crush everything, but P1 has already played both races.
+	odds := map[Matchup]float64{}
+	for k, v := range MatchupsV1d2 {
+		odds[k] = v
+	}
+	for race := range Factions {
+		for _, lord := range []Faction{Katarin, Kostaltyn, Skrag} {
+			odds[Matchup{P1: lord, P2: race}] = .95
+		}
+	}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{Katarin, Kairos}, Matchup: Matchup{P1: Katarin, P2: Skarbrand}, WhoWon: P1},
+			{Picks: []Faction{MiaoYing, Kugath}, Matchup: Matchup{P1: Greasus, P2: Kugath}, WhoWon: P2},
+		},
+		P3Round: P3Round{},
+	}
+
+	values := map[RepeatGranularity]float64{}
+	for _, repeats := range []RepeatGranularity{RaceRepeats, LordRepeats} {
+		tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: odds, Ruleset: Ruleset{FactionPool: AllLords(), Repeats: repeats}}
+		value, line := TurinMinimax(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), -1.0, 2.0)
+		values[repeats] = value
+		played := line.P3Round.Matchup.P1
+		// P2 picks first in the final game and can only ban one of the two lords P1 has left.
+		if repeats == LordRepeats && played != Kostaltyn && played != Skrag {
+			t.Errorf("Expected P1 to go back to Kislev or the Ogres but got %s", played)
+		}
+		if repeats == RaceRepeats && (Race(played) == KI || Race(played) == OK) {
+			t.Errorf("Expected Kislev and the Ogres to be off the table for P1 but got %s", played)
+		}
+	}
+	if !(values[LordRepeats] > values[RaceRepeats]) {
+		t.Errorf("Expected lord repeats to help P1 but got %f against %f", values[LordRepeats], values[RaceRepeats])
+	}
+}
@@ -0,0 +1,99 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+)
+
+/**
+Whether the series still has map bans to make. They come before anything else.
+*/
+func mapBansRemain(tournamentInfo TournamentInfo, gameState GameState) bool {
+	return tournamentInfo.Ruleset.DraftsMaps() && len(gameState.MapBans) < 2*tournamentInfo.Ruleset.MapBansPerPlayer
+}
+
+/**
+Whether the next game, or the final game if the draft has reached it, is still waiting on its map.
+*/
+func mapPickIsNext(tournamentInfo TournamentInfo, gameState GameState) bool {
+	if !tournamentInfo.Ruleset.DraftsMaps() {
+		return false
+	}
+	if isFinalRound(tournamentInfo, gameState) {
+		return gameState.P3Round.Matchup.Map == "" && len(gameState.P3Round.Picks) == 0
+	}
+	if len(gameState.P2Rounds) == 0 {
+		return true
+	}
+	lastRound := gameState.P2Rounds[len(gameState.P2Rounds)-1]
+	return lastRound.Matchup.P1 != EMPTY && lastRound.Matchup.P2 != EMPTY
+}
+
+/**
+Whether P1 picks the map for the game about to be drafted.
+*/
+func isP1MapPicker(tournamentInfo TournamentInfo, gameState GameState) bool {
+	// Either way the game about to start is the len(P2Rounds)th.
+	isP1First := isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds))
+	if tournamentInfo.Ruleset.MapPicker == CounterPickerPicksMap {
+		return !isP1First
+	}
+	return isP1First
+}
+
+func getSuccessorsMap(tournamentInfo TournamentInfo, previousGameState GameState) []GameState {
+	var successors []GameState
+	remainingMaps := getRemainingMaps(tournamentInfo, previousGameState)
+	if mapBansRemain(tournamentInfo, previousGameState) {
+		for _, v := range remainingMaps {
+			newGameState := deepcopy(previousGameState)
+			newGameState.MapBans = append(newGameState.MapBans, v)
+			successors = append(successors, newGameState)
+		}
+		return successors
+	}
+
+	isFinal := isFinalRound(tournamentInfo, previousGameState)
+	for _, v := range remainingMaps {
+		newGameState := deepcopy(previousGameState)
+		if isFinal {
+			newGameState.P3Round.Matchup.Map = v
+		} else {
+			newGameState.P2Rounds = append(newGameState.P2Rounds, P2Round{Matchup: Matchup{Map: v}})
+		}
+		successors = append(successors, newGameState)
+	}
+	return successors
+}
+
+/**
+The maps that haven't been banned or played yet, in pool order. If that leaves nothing, maps that were played become
+available again, and if even that leaves nothing, so do banned ones.
+*/
+func getRemainingMaps(tournamentInfo TournamentInfo, gameState GameState) []GameMap {
+	banned := map[GameMap]bool{}
+	for _, v := range gameState.MapBans {
+		banned[v] = true
+	}
+	played := map[GameMap]bool{}
+	for _, v := range gameState.P2Rounds {
+		played[v.Matchup.Map] = true
+	}
+
+	var unplayed, unbanned []GameMap
+	for _, v := range tournamentInfo.Ruleset.MapPool {
+		if banned[v] {
+			continue
+		}
+		unbanned = append(unbanned, v)
+		if !played[v] {
+			unplayed = append(unplayed, v)
+		}
+	}
+	if len(unplayed) > 0 {
+		return unplayed
+	}
+	if len(unbanned) > 0 {
+		return unbanned
+	}
+	return tournamentInfo.Ruleset.MapPool
+}
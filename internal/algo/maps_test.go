@@ -0,0 +1,134 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+/**
+Matchup odds that are flat .5 everywhere except on favoured, where P1 wins 90% of games whatever the factions.
+*/
+func mapOdds(favoured GameMap) map[Matchup]float64 {
+	odds := map[Matchup]float64{}
+	for f1 := range Factions {
+		for f2 := range Factions {
+			odds[Matchup{P1: f1, P2: f2}] = .5
+			odds[Matchup{P1: f1, P2: f2, Map: favoured}] = .9
+		}
+	}
+	return odds
+}
+
+func TestGetMatchupValueFallsBackToNoMap(t *testing.T) {
+	tournamentInfo := TournamentInfo{
+		MatchupOdds: map[Matchup]float64{
+			{P1: GC, P2: KH}:                  .4,
+			{P1: GC, P2: KH, Map: "Fortress"}: .7,
+		},
+	}
+	cases := []struct {
+		matchup  Matchup
+		expected float64
+	}{
+		{Matchup{P1: GC, P2: KH, Map: "Fortress"}, .7},
+		{Matchup{P1: KH, P2: GC, Map: "Fortress"}, .3},
+		{Matchup{P1: GC, P2: KH, Map: "Village"}, .4},
+		{Matchup{P1: KH, P2: GC, Map: "Village"}, .6},
+	}
+	for _, v := range cases {
+		actual := GetMatchupValue(v.matchup, tournamentInfo)
+		if !(math.Abs(actual-v.expected) < epsilon) {
+			t.Errorf("Expected %+v to be worth %f but got %f", v.matchup, v.expected, actual)
+		}
+	}
+}
+
+func TestMapStepOrder(t *testing.T) {
+	ruleset := Ruleset{
+		MapPool:          []GameMap{"A", "B", "C", "D"},
+		MapBansPerPlayer: 1,
+		MapPicker:        CounterPickerPicksMap,
+	}
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: ruleset}
+
+	type move struct {
+		step DraftStep
+		isP1 bool
+	}
+	expected := []move{
+		{MapBan, true}, {MapBan, false},
+		{MapPick, false}, {InitialPicks, true}, {CounterPick, false}, {FinalPick, true},
+		{MapPick, true}, {InitialPicks, false}, {CounterPick, true}, {FinalPick, false},
+		{MapPick, false}, {LastInitialPicks, true}, {LastCounterPick, false}, {LastFinalPick, true},
+	}
+	var actual []move
+	gameState := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}
+	for !draftIsComplete(tournamentInfo, gameState) {
+		actual = append(actual, move{NextStep(tournamentInfo, gameState), IsP1PickNext(tournamentInfo, gameState)})
+		gameState = getSuccessors(tournamentInfo, gameState)[0]
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected steps %v but got %v", expected, actual)
+	}
+
+	// A and B are banned, C and D get played, and then the pool has run dry so C comes back.
+	if !reflect.DeepEqual(gameState.MapBans, []GameMap{"A", "B"}) ||
+		gameState.P2Rounds[0].Matchup.Map != "C" ||
+		gameState.P2Rounds[1].Matchup.Map != "D" ||
+		gameState.P3Round.Matchup.Map != "C" {
+		t.Errorf("Expected bans A, B and maps C, D, C but got %+v", gameState)
+	}
+}
+
+func TestMapPickerTakesBestMap(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC, Map: "Even"}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH, Map: "Other"}},
+		},
+		P3Round: P3Round{},
+	}
+	for _, mapPicker := range []MapPicker{FirstPickerPicksMap, CounterPickerPicksMap} {
+		ruleset := Ruleset{MapPool: []GameMap{"Even", "Other", "Favoured", "Flat"}, MapPicker: mapPicker}
+		tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: mapOdds("Favoured"), Ruleset: ruleset}
+
+		isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
+		_, line := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+		// P1 is the first picker of the final game, so picks the map themselves unless it goes to the counter picker.
+		expectedMap := GameMap("Favoured")
+		if mapPicker == CounterPickerPicksMap {
+			expectedMap = "Flat"
+		}
+		if isP1PickNext != (mapPicker == FirstPickerPicksMap) || line.P3Round.Matchup.Map != expectedMap {
+			t.Errorf("Expected %s to pick %s but got %s", mapPicker, expectedMap, line.P3Round.Matchup.Map)
+		}
+	}
+}
+
+func TestNeutralMapsDontChangeValue(t *testing.T) {
+	gameState := bo3Positions[2]
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	expected, _ := TurinMinimax(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), -1.0, 2.0)
+
+	tournamentInfo.Ruleset = Ruleset{MapPool: []GameMap{"A", "B"}}
+	actual, line := TurinMinimax(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), -1.0, 2.0)
+	if !(math.Abs(expected-actual) < epsilon) {
+		t.Errorf("Expected maps without odds of their own to leave the value at %f but got %f", expected, actual)
+	}
+	if line.P3Round.Matchup.Map == "" {
+		t.Errorf("Expected the line to pick a map for the final game")
+	}
+}
+
+func TestPoolEvaluatorUsesMapOdds(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: mapOdds("Favoured"), Ruleset: Ruleset{MapPool: []GameMap{"Favoured", "Flat"}}}
+	gameState := GameState{P2Rounds: []P2Round{{Matchup: Matchup{Map: "Flat"}}}, P3Round: P3Round{}}
+	flat := PoolEvaluator(tournamentInfo, gameState)
+	gameState.P2Rounds[0].Matchup.Map = "Favoured"
+	favoured := PoolEvaluator(tournamentInfo, gameState)
+	if !(math.Abs(flat-.5) < epsilon) || !(favoured > flat) {
+		t.Errorf("Expected a game on the favoured map to help P1 but got %f against %f", favoured, flat)
+	}
+}
@@ -0,0 +1,112 @@
+package algo
+
+import (
+	"math"
+)
+
+const simplexEpsilon = 1e-12
+
+/**
+Solves a zero sum matrix game. The row player picks a row and wants the payoff to be high, the column player picks a
+column at the same time and wants it to be low.
+
+Returns the value of the game along with the row and column players' equilibrium mixed strategies - the probability
+of playing each row and each column.
+
+This is the textbook reduction to a linear program. After shifting every payoff to be at least one, the column
+player's problem becomes: maximise sum(y) subject to payoff * y <= 1 and y >= 0. The optimal y scaled to sum to one is
+the column strategy, the game value is 1 / sum(y), and the row strategy falls out of the dual. The program is solved
+with the simplex method using Bland's rule, which is plenty for the handful of options a draft step offers.
+*/
+func solveMatrixGame(payoff [][]float64) (float64, []float64, []float64) {
+	rowCount := len(payoff)
+	colCount := len(payoff[0])
+
+	minPayoff := math.Inf(1)
+	for _, row := range payoff {
+		for _, v := range row {
+			minPayoff = math.Min(minPayoff, v)
+		}
+	}
+	shift := 1.0 - minPayoff
+
+	// One constraint per row with a slack variable each, then the right hand side. The objective row is last.
+	width := colCount + rowCount + 1
+	tableau := make([][]float64, rowCount+1)
+	basis := make([]int, rowCount)
+	for i := 0; i < rowCount; i++ {
+		tableau[i] = make([]float64, width)
+		for j := 0; j < colCount; j++ {
+			tableau[i][j] = payoff[i][j] + shift
+		}
+		tableau[i][colCount+i] = 1.0
+		tableau[i][width-1] = 1.0
+		basis[i] = colCount + i
+	}
+	objective := make([]float64, width)
+	for j := 0; j < colCount; j++ {
+		objective[j] = -1.0
+	}
+	tableau[rowCount] = objective
+
+	for {
+		entering := -1
+		for j := 0; j < width-1; j++ {
+			if objective[j] < -simplexEpsilon {
+				entering = j
+				break
+			}
+		}
+		if entering == -1 {
+			break
+		}
+
+		leaving := -1
+		bestRatio := math.Inf(1)
+		for i := 0; i < rowCount; i++ {
+			if tableau[i][entering] <= simplexEpsilon {
+				continue
+			}
+			ratio := tableau[i][width-1] / tableau[i][entering]
+			if ratio < bestRatio-simplexEpsilon || (leaving != -1 && math.Abs(ratio-bestRatio) <= simplexEpsilon && basis[i] < basis[leaving]) {
+				leaving = i
+				bestRatio = ratio
+			}
+		}
+		// Every payoff is positive after the shift, so the program is bounded and leaving is always found.
+		pivot(tableau, leaving, entering)
+		basis[leaving] = entering
+	}
+
+	sumY := objective[width-1]
+	colStrategy := make([]float64, colCount)
+	for i, v := range basis {
+		if v < colCount {
+			colStrategy[v] = tableau[i][width-1] / sumY
+		}
+	}
+	rowStrategy := make([]float64, rowCount)
+	for i := 0; i < rowCount; i++ {
+		rowStrategy[i] = objective[colCount+i] / sumY
+	}
+	return 1.0/sumY - shift, rowStrategy, colStrategy
+}
+
+func pivot(tableau [][]float64, pivotRow int, pivotCol int) {
+	pivotValue := tableau[pivotRow][pivotCol]
+	for j := range tableau[pivotRow] {
+		tableau[pivotRow][j] /= pivotValue
+	}
+	for i := range tableau {
+		if i == pivotRow {
+			continue
+		}
+		factor := tableau[i][pivotCol]
+		if factor == 0 {
+			continue
+		}
+		for j := range tableau[i] {
+			tableau[i][j] -= factor * tableau[pivotRow][j]
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package algo
+
+import (
+	"math"
+	"testing"
+)
+
+const strategyEpsilon = .000001
+
+/**
+Checks that value, rowStrategy and colStrategy really are an equilibrium of payoff: neither player can beat value by
+switching to any pure strategy.
+*/
+func checkEquilibrium(t *testing.T, payoff [][]float64, value float64, rowStrategy []float64, colStrategy []float64) {
+	checkDistribution(t, rowStrategy)
+	checkDistribution(t, colStrategy)
+	for j := range payoff[0] {
+		rowPlayerGets := 0.0
+		for i := range payoff {
+			rowPlayerGets += rowStrategy[i] * payoff[i][j]
+		}
+		if rowPlayerGets < value-strategyEpsilon {
+			t.Errorf("Column %d holds the row player to %f, below the game value %f", j, rowPlayerGets, value)
+		}
+	}
+	for i := range payoff {
+		colPlayerConcedes := 0.0
+		for j := range payoff[i] {
+			colPlayerConcedes += colStrategy[j] * payoff[i][j]
+		}
+		if colPlayerConcedes > value+strategyEpsilon {
+			t.Errorf("Row %d gets the row player %f, above the game value %f", i, colPlayerConcedes, value)
+		}
+	}
+}
+
+func checkDistribution(t *testing.T, strategy []float64) {
+	total := 0.0
+	for _, v := range strategy {
+		if v < -strategyEpsilon {
+			t.Errorf("Expected probabilities but got %+v", strategy)
+		}
+		total += v
+	}
+	if !(math.Abs(total-1.0) < strategyEpsilon) {
+		t.Errorf("Expected %+v to sum to 1 but it summed to %f", strategy, total)
+	}
+}
+
+func TestSolveMatrixGameMatchingPennies(t *testing.T) {
+	payoff := [][]float64{
+		{1.0, 0.0},
+		{0.0, 1.0},
+	}
+	value, rowStrategy, colStrategy := solveMatrixGame(payoff)
+
+	expected := .5
+	if !(math.Abs(value-expected) < strategyEpsilon) {
+		t.Errorf("Expected value to be %f but it was %f", expected, value)
+	}
+	if !(math.Abs(rowStrategy[0]-.5) < strategyEpsilon) || !(math.Abs(colStrategy[0]-.5) < strategyEpsilon) {
+		t.Errorf("Expected even strategies but got %+v and %+v", rowStrategy, colStrategy)
+	}
+	checkEquilibrium(t, payoff, value, rowStrategy, colStrategy)
+}
+
+func TestSolveMatrixGameRockPaperScissors(t *testing.T) {
+	payoff := [][]float64{
+		{.5, 0.0, 1.0},
+		{1.0, .5, 0.0},
+		{0.0, 1.0, .5},
+	}
+	value, rowStrategy, colStrategy := solveMatrixGame(payoff)
+
+	expected := .5
+	if !(math.Abs(value-expected) < strategyEpsilon) {
+		t.Errorf("Expected value to be %f but it was %f", expected, value)
+	}
+	for i := range rowStrategy {
+		if !(math.Abs(rowStrategy[i]-1.0/3.0) < strategyEpsilon) || !(math.Abs(colStrategy[i]-1.0/3.0) < strategyEpsilon) {
+			t.Errorf("Expected uniform strategies but got %+v and %+v", rowStrategy, colStrategy)
+		}
+	}
+	checkEquilibrium(t, payoff, value, rowStrategy, colStrategy)
+}
+
+func TestSolveMatrixGameSaddlePoint(t *testing.T) {
+	payoff := [][]float64{
+		{.6, .7},
+		{.4, .3},
+	}
+	value, rowStrategy, colStrategy := solveMatrixGame(payoff)
+
+	expected := .6
+	if !(math.Abs(value-expected) < strategyEpsilon) {
+		t.Errorf("Expected value to be %f but it was %f", expected, value)
+	}
+	if !(math.Abs(rowStrategy[0]-1.0) < strategyEpsilon) || !(math.Abs(colStrategy[0]-1.0) < strategyEpsilon) {
+		t.Errorf("Expected pure strategies but got %+v and %+v", rowStrategy, colStrategy)
+	}
+	checkEquilibrium(t, payoff, value, rowStrategy, colStrategy)
+}
+
+func TestSolveMatrixGameUneven(t *testing.T) {
+	payoff := [][]float64{
+		{.2, .9, .55, .4},
+		{.8, .1, .45, .6},
+		{.5, .5, .3, .65},
+	}
+	value, rowStrategy, colStrategy := solveMatrixGame(payoff)
+
+	checkEquilibrium(t, payoff, value, rowStrategy, colStrategy)
+}
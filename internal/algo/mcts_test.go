@@ -0,0 +1,112 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+/**
+Exact minimax value of the first pick solver makes from gameState, alongside the value of the best first pick.
+*/
+func compareFirstPickToMinimax(t *testing.T, solver Solver, tournamentInfo TournamentInfo, gameState GameState) (float64, float64) {
+	isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
+	bestVal, _ := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+	_, line := solver.Solve(tournamentInfo, gameState, isP1PickNext)
+	for _, v := range getSuccessors(tournamentInfo, gameState) {
+		if isAncestor(v, line) {
+			chosenVal, _ := TurinMinimax(tournamentInfo, v, IsP1PickNext(tournamentInfo, v), -1.0, 2.0)
+			return chosenVal, bestVal
+		}
+	}
+	t.Fatalf("Line %+v does not follow from %+v", line, gameState)
+	return 0, 0
+}
+
+func TestMCTSMatchesMinimaxBo3(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	solver := MCTSSolver{Iterations: 5000, Seed: 1}
+
+	for _, gameState := range bo3Positions[1:] {
+		chosenVal, bestVal := compareFirstPickToMinimax(t, solver, tournamentInfo, gameState)
+		if !(math.Abs(chosenVal-bestVal) < epsilon) {
+			t.Errorf("Expected MCTS pick from %+v to be worth %f but it was worth %f", gameState, bestVal, chosenVal)
+		}
+	}
+}
+
+func TestMCTSMatchesMinimaxPolarized(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: GC}},
+			{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: KH}},
+		},
+		P3Round: P3Round{},
+	}
+	solver := MCTSSolver{Iterations: 5000, Seed: 7}
+
+	chosenVal, bestVal := compareFirstPickToMinimax(t, solver, tournamentInfo, gameState)
+	if !(math.Abs(chosenVal-bestVal) < epsilon) {
+		t.Errorf("Expected MCTS pick to be worth %f but it was worth %f", bestVal, chosenVal)
+	}
+}
+
+func TestMCTSSameSeedSameResult(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := bo3Positions[1]
+	solver := MCTSSolver{Iterations: 2000, Exploration: .5, Seed: 42}
+
+	value1, gameState1 := solver.Solve(tournamentInfo, gameState, true)
+	value2, gameState2 := solver.Solve(tournamentInfo, gameState, true)
+	if value1 != value2 || !reflect.DeepEqual(gameState1, gameState2) {
+		t.Errorf("Expected identical results but got %f %+v and %f %+v", value1, gameState1, value2, gameState2)
+	}
+	if !draftIsComplete(tournamentInfo, gameState1) {
+		t.Errorf("Expected a complete line but got %+v", gameState1)
+	}
+}
+
+func TestMCTSCompleteDraft(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+		},
+		P3Round: P3Round{
+			Picks:      []Faction{GC, KI, NG},
+			Ban:        SL,
+			CounterBan: NG,
+			Matchup:    Matchup{P1: KI, P2: TZ}},
+	}
+
+	value, _ := MCTSSolver{}.Solve(tournamentInfo, gameState, true)
+	expected := computeWinRate(tournamentInfo, gameState)
+	if !(math.Abs(value-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, value)
+	}
+}
+
+func TestMCTSSamplesResultsByOdds(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized, Ruleset: Ruleset{FirstPick: LoserPicksFirst}}
+	// GC always beats KH, so P2 winning the first game can't happen and mustn't be searched.
+	gameState := GameState{P2Rounds: []P2Round{{Picks: []Faction{GC, KI}, Matchup: Matchup{P1: GC, P2: KH}}}}
+	node := newMCTSNode(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState))
+	if !node.isResult {
+		t.Fatalf("Expected %+v to wait on a result", gameState)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		child, _ := node.sampleResult(tournamentInfo, rng)
+		if child.gameState.P2Rounds[0].WhoWon != P1 {
+			t.Fatalf("Expected only P1 wins to be sampled but got %+v", child.gameState.P2Rounds[0])
+		}
+	}
+	if len(node.children) != 1 || len(node.untried) != 1 {
+		t.Errorf("Expected only the P1 win to be expanded but got %d children and %d untried", len(node.children), len(node.untried))
+	}
+}
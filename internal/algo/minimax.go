@@ -3,19 +3,259 @@ package algo
 import (
 	. "github.com/tmwilder/wh3-draftbot/internal/common"
 	"math"
+	"time"
 )
 
+// search carries the settings shared by every node of a single minimax run.
+type search struct {
+	tournamentInfo TournamentInfo
+	evaluator      Evaluator
+	// objective scores complete drafts in place of P1's series win rate when set.
+	objective Objective
+	deadline  time.Time
+	// horizonHit records that at least one line was cut off and scored by the evaluator rather than played out.
+	horizonHit bool
+	timedOut   bool
+	// table, if set, keeps the positions this search solves exactly and supplies those other searches have.
+	table *TranspositionTable
+	// Counted for SearchStats. ply is how many steps below the searched position the current node is.
+	nodes             int
+	leaves            int
+	cutoffs           int
+	transpositionHits int
+	ply               int
+	maxDepth          int
+}
+
+/**
+SearchStats is how much work a search did. Leaves are the drafts it scored as they stood, whether complete or cut off by
+its depth or budget. Cutoffs count the nodes where alpha-beta pruning skipped the rest of the moves. MaxDepth is the
+most steps below the searched position it looked, counting game results as steps.
+*/
+type SearchStats struct {
+	Nodes             int
+	Leaves            int
+	Cutoffs           int
+	TranspositionHits int
+	MaxDepth          int
+	Elapsed           time.Duration
+}
+
+/**
+The work of two searches together, e.g. those that went into one recommendation.
+*/
+func (s SearchStats) Add(other SearchStats) SearchStats {
+	return SearchStats{
+		Nodes:             s.Nodes + other.Nodes,
+		Leaves:            s.Leaves + other.Leaves,
+		Cutoffs:           s.Cutoffs + other.Cutoffs,
+		TranspositionHits: s.TranspositionHits + other.TranspositionHits,
+		MaxDepth:          int(math.Max(float64(s.MaxDepth), float64(other.MaxDepth))),
+		Elapsed:           s.Elapsed + other.Elapsed,
+	}
+}
+
+// SearchOptions picks what Search looks for and how long it may take. The zero value searches P1's series win rate to
+// the end of the draft.
+type SearchOptions struct {
+	// Objective is searched in place of the series win rate if set. Searches for an objective always go to the end.
+	Objective Objective
+	// Budget caps how long a search for the series win rate may take, as in TurinMinimaxTimed. Zero means no limit.
+	Budget time.Duration
+	// Evaluator scores lines the budget cuts off, PoolEvaluator if unset.
+	Evaluator Evaluator
+	// Table, if set, is shared with other searches from the same position, which reuse whatever they solve in common.
+	Table *TranspositionTable
+}
+
+/**
+TranspositionTable keeps the positions a search solves exactly, so that a later search reaching them takes the result
+rather than searching them again. A draft never reaches the same position two ways, as every step is written down in
+order, so the hits come from searches that cover the same ground: finding the best line and then the odds of each final
+score, a blind step searched within the line and then solved on its own, or the moves of a draft review.
+
+Only positions searched to the end with the widest window are kept, as only those values are exact. Searches cut short
+by a depth or time budget still take what the table has. A table must only be shared between searches of the same
+format, matchup odds and objective, and is not safe for concurrent use.
+*/
+type TranspositionTable struct {
+	entries map[string]transposition
+}
+
+type transposition struct {
+	value float64
+	line  GameState
+}
+
+func NewTranspositionTable() *TranspositionTable {
+	return &TranspositionTable{entries: map[string]transposition{}}
+}
+
+func (t *TranspositionTable) Len() int {
+	return len(t.entries)
+}
+
+/**
+Who is taken to move is part of the key, as P2's search counts some positions where nobody moves as P1's turn.
+*/
+func transpositionKey(gameState GameState, isMaximizingPlayer bool) string {
+	if isMaximizingPlayer {
+		return "max " + PositionKey(gameState)
+	}
+	return "min " + PositionKey(gameState)
+}
+
 func TurinMinimax(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, alpha float64, beta float64) (float64, GameState) {
+	s := search{tournamentInfo: tournamentInfo}
+	return s.minimax(gameState, isMaximizingPlayer, alpha, beta, -1)
+}
+
+/**
+Finds the best line from gameState as set out by options, and reports how much work that took alongside the value.
+*/
+func Search(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, options SearchOptions) (float64, GameState, SearchStats) {
+	start := time.Now()
+	var value float64
+	var line GameState
+	var stats SearchStats
+	if options.Objective == nil && options.Budget > 0 {
+		value, line = turinMinimaxTimed(tournamentInfo, gameState, isMaximizingPlayer, options.Budget, evaluatorOrDefault(options.Evaluator), options.Table, &stats)
+	} else {
+		s := search{tournamentInfo: tournamentInfo, objective: options.Objective, table: options.Table}
+		low, high := s.bounds()
+		value, line = s.minimax(gameState, isMaximizingPlayer, low-1.0, high+1.0, -1)
+		stats = s.stats()
+	}
+	stats.Elapsed = time.Since(start)
+	return value, line, stats
+}
+
+/**
+Like TurinMinimax, but P1 maximises objective rather than their series win rate and P2 minimises it. The returned
+value is in objective's terms.
+*/
+func TurinMinimaxObjective(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, objective Objective) (float64, GameState) {
+	s := search{tournamentInfo: tournamentInfo, objective: objective}
+	low, high := s.bounds()
+	return s.minimax(gameState, isMaximizingPlayer, low-1.0, high+1.0, -1)
+}
+
+/**
+Like TurinMinimax, but only looks depth picks ahead. Drafts that are still unfinished at that point are scored with
+evaluator, so the returned GameState may stop short of a complete draft.
+*/
+func TurinMinimaxDepthLimited(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, alpha float64, beta float64, depth int, evaluator Evaluator) (float64, GameState) {
+	s := search{tournamentInfo: tournamentInfo, evaluator: evaluator}
+	return s.minimax(gameState, isMaximizingPlayer, alpha, beta, depth)
+}
+
+/**
+Searches one pick deeper at a time until either the draft is solved exactly or budget runs out, and returns the
+deepest fully searched result. If not even a one pick search finishes in time, the evaluator's score for gameState is
+returned instead.
+*/
+func TurinMinimaxTimed(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, budget time.Duration, evaluator Evaluator) (float64, GameState) {
+	return turinMinimaxTimed(tournamentInfo, gameState, isMaximizingPlayer, budget, evaluator, nil, &SearchStats{})
+}
+
+/**
+TurinMinimaxTimed, adding the nodes of every search it runs to stats. Positions table has solved exactly are taken from
+it rather than searched.
+*/
+func turinMinimaxTimed(tournamentInfo TournamentInfo, gameState GameState, isMaximizingPlayer bool, budget time.Duration, evaluator Evaluator, table *TranspositionTable, stats *SearchStats) (float64, GameState) {
 	if draftIsComplete(tournamentInfo, gameState) {
 		return computeWinRate(tournamentInfo, gameState), gameState
 	}
 
+	bestVal, bestGameState := evaluator(tournamentInfo, gameState), gameState
+	deadline := time.Now().Add(budget)
+	for depth := 1; ; depth++ {
+		s := search{tournamentInfo: tournamentInfo, evaluator: evaluator, deadline: deadline, table: table}
+		value, candidateGameState := s.minimax(gameState, isMaximizingPlayer, -1.0, 2.0, depth)
+		*stats = stats.Add(s.stats())
+		if s.timedOut {
+			return bestVal, bestGameState
+		}
+		bestVal, bestGameState = value, candidateGameState
+		if !s.horizonHit {
+			return bestVal, bestGameState
+		}
+	}
+}
+
+/**
+A negative depth searches to the end of the draft.
+*/
+func (s *search) minimax(gameState GameState, isMaximizingPlayer bool, alpha float64, beta float64, depth int) (float64, GameState) {
+	tournamentInfo := s.tournamentInfo
+	s.nodes++
+	s.maxDepth = int(math.Max(float64(s.maxDepth), float64(s.ply)))
+	if draftIsComplete(tournamentInfo, gameState) {
+		s.leaves++
+		return s.value(gameState), gameState
+	}
+
+	if depth == 0 {
+		s.horizonHit = true
+		s.leaves++
+		return s.evaluator(tournamentInfo, gameState), gameState
+	}
+
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.timedOut = true
+		s.leaves++
+		return s.evaluator(tournamentInfo, gameState), gameState
+	}
+
+	s.ply++
+	defer func() { s.ply-- }()
+	if s.table == nil {
+		return s.expand(gameState, isMaximizingPlayer, alpha, beta, depth)
+	}
+	// Only a search with the widest window finds an exact value to keep.
+	if low, high := s.bounds(); alpha >= low || beta <= high {
+		return s.expand(gameState, isMaximizingPlayer, alpha, beta, depth)
+	}
+	key := transpositionKey(gameState, isMaximizingPlayer)
+	// Exact values beat anything a depth limited search would find, so they are used at any depth.
+	if entry, ok := s.table.entries[key]; ok {
+		s.transpositionHits++
+		return entry.value, entry.line
+	}
+	if depth >= 0 {
+		return s.expand(gameState, isMaximizingPlayer, alpha, beta, depth)
+	}
+	value, line := s.expand(gameState, isMaximizingPlayer, alpha, beta, depth)
+	s.table.entries[key] = transposition{value: value, line: line}
+	return value, line
+}
+
+/**
+Searches each of gameState's successors, which minimax has found it to have.
+*/
+func (s *search) expand(gameState GameState, isMaximizingPlayer bool, alpha float64, beta float64, depth int) (float64, GameState) {
+	tournamentInfo := s.tournamentInfo
+	low, high := s.bounds()
+	if resultIsNext(tournamentInfo, gameState) {
+		// Results aren't picks, so they don't count against depth. Each one is searched with a full window because
+		// only exact values can be averaged.
+		return expectOverResult(tournamentInfo, gameState, func(v GameState) (float64, GameState) {
+			return s.minimax(v, IsP1PickNext(tournamentInfo, v), low-1.0, high+1.0, depth)
+		})
+	}
+
+	if len(tournamentInfo.Ruleset.SimultaneousSteps) > 0 && tournamentInfo.Ruleset.IsSimultaneous(NextStep(tournamentInfo, gameState)) {
+		solution, line := s.simultaneous(gameState, depth)
+		return solution.Value, line
+	}
+
 	if isMaximizingPlayer {
-		bestVal := -1.0
+		bestVal := low - 1.0
 		var bestGameState GameState
 		successors := getSuccessors(tournamentInfo, gameState)
 		for _, v := range successors {
-			value, candidateGameState := TurinMinimax(tournamentInfo, v, false, alpha, beta)
+			isMaximizingPlayerNext := IsP1PickNext(tournamentInfo, v)
+			value, candidateGameState := s.minimax(v, isMaximizingPlayerNext, alpha, beta, depth-1)
 
 			if value > bestVal {
 				bestGameState = candidateGameState
@@ -24,22 +264,25 @@ func TurinMinimax(tournamentInfo TournamentInfo, gameState GameState, isMaximizi
 			bestVal = math.Max(bestVal, value)
 			alpha = math.Max(alpha, bestVal)
 			if beta <= alpha {
+				if isMaximizingPlayerNext {
+					// Same as for P2 below - P1 can take two turns in a row around map picks.
+					continue
+				}
+				s.cutoffs++
 				break
 			}
 		}
 		return bestVal, bestGameState
 	} else {
-		bestVal := 2.0
+		bestVal := high + 1.0
 		var bestGameState GameState
 		successors := getSuccessors(tournamentInfo, gameState)
 		for _, v := range successors {
-			isMaximizingPlayerNext := true
-			if finalRoundIsNext(tournamentInfo, v) && whoWonTheLastRound(v) == P2 {
-				// If it's the round before last and P2 just won, p2 goes again.
-				// This only happens for p2 because 2nd to last round is always even.
-				isMaximizingPlayerNext = false
-			}
-			value, candidateGameState := TurinMinimax(tournamentInfo, v, isMaximizingPlayerNext, alpha, beta)
+			// If it's the round before last and P2 just won, p2 goes again. Map picks can also give either player two
+			// turns in a row. Nobody moves after the last pick or before a game's result, so count those as P1's turn
+			// to keep pruning.
+			isMaximizingPlayerNext := draftIsComplete(tournamentInfo, v) || resultIsNext(tournamentInfo, v) || IsP1PickNext(tournamentInfo, v)
+			value, candidateGameState := s.minimax(v, isMaximizingPlayerNext, alpha, beta, depth-1)
 
 			if value < bestVal {
 				bestGameState = candidateGameState
@@ -52,6 +295,7 @@ func TurinMinimax(tournamentInfo TournamentInfo, gameState GameState, isMaximizi
 					// Don't alpha/beta prune if we're taking two turns in a row because it's the second to last round.
 					continue
 				}
+				s.cutoffs++
 				break
 			}
 		}
@@ -59,15 +303,50 @@ func TurinMinimax(tournamentInfo TournamentInfo, gameState GameState, isMaximizi
 	}
 }
 
-func draftIsComplete(tournamentInfo TournamentInfo, gameState GameState) bool {
-	return ((len(gameState.P2Rounds) + 1) == tournamentInfo.RoundCount) &&
-		(gameState.P3Round.Matchup.P1 != EMPTY && gameState.P3Round.Matchup.P2 != EMPTY)
+func (s *search) stats() SearchStats {
+	return SearchStats{
+		Nodes:             s.nodes,
+		Leaves:            s.leaves,
+		Cutoffs:           s.cutoffs,
+		TranspositionHits: s.transpositionHits,
+		MaxDepth:          s.maxDepth,
+	}
+}
+
+/**
+What a complete draft is worth to P1: its value under the objective, or their series win rate without one.
+*/
+func (s *search) value(gameState GameState) float64 {
+	if s.objective == nil {
+		return computeWinRate(s.tournamentInfo, gameState)
+	}
+	return ObjectiveValue(s.tournamentInfo, gameState, s.objective)
+}
+
+/**
+The range of values complete drafts can take, which the search starts each node's best value just outside of.
+*/
+func (s *search) bounds() (float64, float64) {
+	if s.objective == nil {
+		return 0.0, 1.0
+	}
+	return s.objective.Bounds(s.tournamentInfo)
 }
 
-func finalRoundIsNext(tournamentInfo TournamentInfo, gameState GameState) bool {
-	return (len(gameState.P2Rounds) == tournamentInfo.RoundCount-1) && (len(gameState.P3Round.Picks) == 0)
+/**
+Whether gameState is a finished draft, which tells a solved line apart from one that a limited search cut off.
+*/
+func IsDraftComplete(tournamentInfo TournamentInfo, gameState GameState) bool {
+	return draftIsComplete(tournamentInfo, gameState)
 }
 
-func whoWonTheLastRound(gameState GameState) WhoWon {
-	return gameState.P2Rounds[len(gameState.P2Rounds)-1].WhoWon
+/**
+A draft is complete once the final game's matchup is set, or as soon as recorded results decide the series.
+*/
+func draftIsComplete(tournamentInfo TournamentInfo, gameState GameState) bool {
+	if seriesWinner(tournamentInfo, gameState) != NoOneYet {
+		return true
+	}
+	return ((len(gameState.P2Rounds) + 1) == tournamentInfo.RoundCount) &&
+		(gameState.P3Round.Matchup.P1 != EMPTY && gameState.P3Round.Matchup.P2 != EMPTY)
 }
@@ -9,40 +9,40 @@ import (
 )
 
 var matchupsPolarized = map[Matchup]float64{
-	Matchup{GC, GC}: .5,
-	Matchup{GC, KH}: 1.0,
-	Matchup{GC, KI}: 1.0,
-	Matchup{GC, NG}: 1.0,
-	Matchup{GC, OK}: 1.0,
-	Matchup{GC, SL}: 1.0,
-	Matchup{GC, TZ}: 1.0,
-
-	Matchup{KH, KH}: 0.5,
-	Matchup{KH, KI}: 1.0,
-	Matchup{KH, NG}: 1.0,
-	Matchup{KH, OK}: 1.0,
-	Matchup{KH, SL}: 1.0,
-	Matchup{KH, TZ}: 1.0,
-
-	Matchup{KI, KI}: 0.5,
-	Matchup{KI, NG}: 1.0,
-	Matchup{KI, OK}: 1.0,
-	Matchup{KI, SL}: 1.0,
-	Matchup{KI, TZ}: 1.0,
-
-	Matchup{NG, NG}: 0.5,
-	Matchup{NG, OK}: 1.0,
-	Matchup{NG, SL}: 1.0,
-	Matchup{NG, TZ}: 1.0,
-
-	Matchup{OK, OK}: 0.5,
-	Matchup{OK, SL}: 0.0,
-	Matchup{OK, TZ}: 0.0,
-
-	Matchup{SL, SL}: 0.5,
-	Matchup{SL, TZ}: 0.0,
-
-	Matchup{TZ, TZ}: 0.5,
+	Matchup{P1: GC, P2: GC}: .5,
+	Matchup{P1: GC, P2: KH}: 1.0,
+	Matchup{P1: GC, P2: KI}: 1.0,
+	Matchup{P1: GC, P2: NG}: 1.0,
+	Matchup{P1: GC, P2: OK}: 1.0,
+	Matchup{P1: GC, P2: SL}: 1.0,
+	Matchup{P1: GC, P2: TZ}: 1.0,
+
+	Matchup{P1: KH, P2: KH}: 0.5,
+	Matchup{P1: KH, P2: KI}: 1.0,
+	Matchup{P1: KH, P2: NG}: 1.0,
+	Matchup{P1: KH, P2: OK}: 1.0,
+	Matchup{P1: KH, P2: SL}: 1.0,
+	Matchup{P1: KH, P2: TZ}: 1.0,
+
+	Matchup{P1: KI, P2: KI}: 0.5,
+	Matchup{P1: KI, P2: NG}: 1.0,
+	Matchup{P1: KI, P2: OK}: 1.0,
+	Matchup{P1: KI, P2: SL}: 1.0,
+	Matchup{P1: KI, P2: TZ}: 1.0,
+
+	Matchup{P1: NG, P2: NG}: 0.5,
+	Matchup{P1: NG, P2: OK}: 1.0,
+	Matchup{P1: NG, P2: SL}: 1.0,
+	Matchup{P1: NG, P2: TZ}: 1.0,
+
+	Matchup{P1: OK, P2: OK}: 0.5,
+	Matchup{P1: OK, P2: SL}: 0.0,
+	Matchup{P1: OK, P2: TZ}: 0.0,
+
+	Matchup{P1: SL, P2: SL}: 0.5,
+	Matchup{P1: SL, P2: TZ}: 0.0,
+
+	Matchup{P1: TZ, P2: TZ}: 0.5,
 }
 
 func TestMinimaxFullGame5(t *testing.T) {
@@ -179,3 +179,43 @@ func TestMinimaxR3Polarized(t *testing.T) {
 		t.Errorf("Expected WR to be %f but it was %f", expected, winRate)
 	}
 }
+
+func TestSearchMatchesMinimax(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+		},
+		P3Round: P3Round{},
+	}
+
+	expected, expectedGameState := TurinMinimax(tournamentInfo, gameState, true, -1.0, 2.0)
+	value, line, stats := Search(tournamentInfo, gameState, true, SearchOptions{})
+
+	if !(math.Abs(value-expected) < epsilon) {
+		t.Errorf("Expected WR to be %f but it was %f", expected, value)
+	}
+	if !(reflect.DeepEqual(expectedGameState, line)) {
+		t.Errorf("Expected %+v but got %+v", expectedGameState, line)
+	}
+	if stats.Nodes <= 1 {
+		t.Errorf("Expected the search to visit more than one node but it visited %d", stats.Nodes)
+	}
+	if stats.Leaves <= 0 || stats.Leaves >= stats.Nodes {
+		t.Errorf("Expected some but not all nodes to be leaves but got %+v", stats)
+	}
+	if stats.Cutoffs <= 0 {
+		t.Errorf("Expected alpha-beta to prune somewhere but got %+v", stats)
+	}
+	// Every line runs the same number of steps to the end of the draft.
+	steps := 0
+	for v := gameState; !draftIsComplete(tournamentInfo, v); v = getSuccessors(tournamentInfo, v)[0] {
+		steps++
+	}
+	if stats.MaxDepth != steps {
+		t.Errorf("Expected a max depth of %d but got %+v", steps, stats)
+	}
+	if stats.TranspositionHits != 0 {
+		t.Errorf("Expected no transposition hits without a table but got %+v", stats)
+	}
+}
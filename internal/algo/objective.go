@@ -0,0 +1,127 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+)
+
+// Objective is what a complete draft is worth to P1, going by the odds of each final score. A search with an objective
+// maximises it rather than P1's series win rate.
+type Objective interface {
+	Value(tournamentInfo TournamentInfo, scores []ScoreOdds) float64
+	// Bounds are the lowest and highest values any draft can take, which the search uses as its starting window.
+	Bounds(tournamentInfo TournamentInfo) (float64, float64)
+}
+
+// ScoreValue is an Objective that values each final score on its own and takes the expected value over them.
+type ScoreValue func(score Score) float64
+
+func (f ScoreValue) Value(tournamentInfo TournamentInfo, scores []ScoreOdds) float64 {
+	value := 0.0
+	for _, v := range scores {
+		value += v.Odds * f(v.Score)
+	}
+	return value
+}
+
+func (f ScoreValue) Bounds(tournamentInfo TournamentInfo) (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range finalScores(tournamentInfo) {
+		low = math.Min(low, f(v))
+		high = math.Max(high, f(v))
+	}
+	return low, high
+}
+
+// SeriesWin is worth one for winning the series and nothing for losing it, the same as searching on series win rate.
+var SeriesWin = ScoreValue(func(score Score) float64 {
+	if score.P1 > score.P2 {
+		return 1.0
+	}
+	return 0.0
+})
+
+// GameWins is worth a point for every game P1 wins, so 2-0 beats 2-1 and 1-2 beats 0-2.
+var GameWins = ScoreValue(func(score Score) float64 {
+	return float64(score.P1)
+})
+
+// AtLeastOneGame is worth one for taking any game of the series, for players who mostly want to avoid a whitewash.
+var AtLeastOneGame = ScoreValue(func(score Score) float64 {
+	if score.P1 > 0 {
+		return 1.0
+	}
+	return 0.0
+})
+
+/**
+Values each final score by points, with scores that are left out worth nothing. A league giving three points for a
+2-0, two for a 2-1 and one for a 1-2 would be {2-0: 3, 2-1: 2, 1-2: 1}.
+*/
+func PointsTable(points map[Score]float64) ScoreValue {
+	return func(score Score) float64 {
+		return points[score]
+	}
+}
+
+/**
+League points for a series of any length: win points for winning it, loss points for losing it after taking at least
+one game, and nothing for losing every game. 3/1/0 is LeaguePoints(3, 1).
+*/
+func LeaguePoints(win float64, loss float64) ScoreValue {
+	return func(score Score) float64 {
+		if score.P1 > score.P2 {
+			return win
+		}
+		if score.P1 > 0 {
+			return loss
+		}
+		return 0.0
+	}
+}
+
+/**
+VariancePenalty is a risk averse Objective. It takes the expected value of Score less Penalty times its variance, so
+the search gives up some expected value for a steadier result.
+
+The search averages over game results wherever the draft waits on one, which is only exact for expected values, so
+under rulesets that depend on results the penalty is an approximation.
+*/
+type VariancePenalty struct {
+	Score   ScoreValue
+	Penalty float64
+}
+
+func (v VariancePenalty) Value(tournamentInfo TournamentInfo, scores []ScoreOdds) float64 {
+	mean := v.Score.Value(tournamentInfo, scores)
+	variance := 0.0
+	for _, scoreOdds := range scores {
+		variance += scoreOdds.Odds * math.Pow(v.Score(scoreOdds.Score)-mean, 2)
+	}
+	return mean - v.Penalty*variance
+}
+
+func (v VariancePenalty) Bounds(tournamentInfo TournamentInfo) (float64, float64) {
+	low, high := v.Score.Bounds(tournamentInfo)
+	// Variance peaks at an even split between the two extremes.
+	return low - v.Penalty*math.Pow(high-low, 2)/4, high
+}
+
+/**
+What a complete draft is worth to P1 under objective.
+*/
+func ObjectiveValue(tournamentInfo TournamentInfo, gameState GameState, objective Objective) float64 {
+	return objective.Value(tournamentInfo, ScoreDistribution(tournamentInfo, gameState))
+}
+
+/**
+Every score a series of tournamentInfo's length can end on.
+*/
+func finalScores(tournamentInfo TournamentInfo) []Score {
+	needed := tournamentInfo.RoundCount/2 + 1
+	var scores []Score
+	for losses := 0; losses < needed; losses++ {
+		scores = append(scores, Score{P1: needed, P2: losses}, Score{P1: losses, P2: needed})
+	}
+	return scores
+}
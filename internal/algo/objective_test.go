@@ -0,0 +1,105 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestObjectiveBounds(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2}
+	if actual := finalScores(tournamentInfo); len(actual) != 6 {
+		t.Errorf("Expected a Bo5 to end on one of six scores but got %v", actual)
+	}
+	if low, high := GameWins.Bounds(tournamentInfo); low != 0 || high != 3 {
+		t.Errorf("Expected P1 to win from 0 to 3 games of a Bo5 but got %f to %f", low, high)
+	}
+	leaguePoints := PointsTable(map[Score]float64{{P1: 3, P2: 0}: 3, {P1: 3, P2: 1}: 3, {P1: 3, P2: 2}: 2, {P1: 2, P2: 3}: 1})
+	if low, high := leaguePoints.Bounds(tournamentInfo); low != 0 || high != 3 {
+		t.Errorf("Expected league points from 0 to 3 but got %f to %f", low, high)
+	}
+	// The worst a penalty can do is a coin flip between no games and three.
+	if low, high := (VariancePenalty{Score: GameWins, Penalty: .5}).Bounds(tournamentInfo); low != -1.125 || high != 3 {
+		t.Errorf("Expected a penalised game count from -1.125 to 3 but got %f to %f", low, high)
+	}
+}
+
+func TestObjectiveValue(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: KI}},
+			{Picks: []Faction{NG, SL}, Matchup: Matchup{P1: KH, P2: NG}},
+		},
+		P3Round: P3Round{Picks: []Faction{KI, NG, OK}, Ban: TZ, CounterBan: KI, Matchup: Matchup{P1: OK, P2: TZ}},
+	}
+	odds := map[Score]float64{}
+	for _, v := range ScoreDistribution(defaultTournamentInfo, gameState) {
+		odds[v.Score] = v.Odds
+	}
+
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, SeriesWin); !(math.Abs(actual-computeWinRate(defaultTournamentInfo, gameState)) < epsilon) {
+		t.Errorf("Expected the series win objective to be worth P1's win rate but got %f", actual)
+	}
+	expected := 2*odds[Score{P1: 2, P2: 0}] + 2*odds[Score{P1: 2, P2: 1}] + odds[Score{P1: 1, P2: 2}]
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, GameWins); !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected P1 to win %f games on average but got %f", expected, actual)
+	}
+	expected = 3*odds[Score{P1: 2, P2: 0}] + 2*odds[Score{P1: 2, P2: 1}] + odds[Score{P1: 1, P2: 2}]
+	leaguePoints := PointsTable(map[Score]float64{{P1: 2, P2: 0}: 3, {P1: 2, P2: 1}: 2, {P1: 1, P2: 2}: 1})
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, leaguePoints); !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected P1 to score %f league points on average but got %f", expected, actual)
+	}
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, LeaguePoints(3, 1)); !(math.Abs(actual-(expected+odds[Score{P1: 2, P2: 1}])) < epsilon) {
+		t.Errorf("Expected 3/1/0 league points to give a 2-1 the same as a 2-0 but got %f", actual)
+	}
+	expected = 1 - odds[Score{P1: 0, P2: 2}]
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, AtLeastOneGame); !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected P1 to take a game with odds %f but got %f", expected, actual)
+	}
+
+	mean := ObjectiveValue(defaultTournamentInfo, gameState, GameWins)
+	variance := 0.0
+	for score, v := range odds {
+		variance += v * math.Pow(float64(score.P1)-mean, 2)
+	}
+	riskAverse := VariancePenalty{Score: GameWins, Penalty: .5}
+	if actual := ObjectiveValue(defaultTournamentInfo, gameState, riskAverse); !(math.Abs(actual-(mean-.5*variance)) < epsilon) {
+		t.Errorf("Expected the penalised game count to be %f but got %f", mean-.5*variance, actual)
+	}
+}
+
+func TestMinimaxObjective(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: KI}},
+			{Picks: []Faction{NG, SL}},
+		},
+	}
+	isP1PickNext := IsP1PickNext(defaultTournamentInfo, gameState)
+
+	winRate, line := TurinMinimax(defaultTournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+	value, objectiveLine := TurinMinimaxObjective(defaultTournamentInfo, gameState, isP1PickNext, SeriesWin)
+	if !(math.Abs(value-winRate) < epsilon) || !reflect.DeepEqual(line, objectiveLine) {
+		t.Errorf("Expected the series win objective to find %f along %+v but got %f along %+v", winRate, line, value, objectiveLine)
+	}
+
+	value, line = TurinMinimaxObjective(defaultTournamentInfo, gameState, isP1PickNext, GameWins)
+	if !draftIsComplete(defaultTournamentInfo, line) || !(math.Abs(value-ObjectiveValue(defaultTournamentInfo, line, GameWins)) < epsilon) {
+		t.Errorf("Expected a complete line worth %f game wins but got %+v", value, line)
+	}
+
+	// Values well past a series win rate's range still need a line found for them.
+	leaguePoints := PointsTable(map[Score]float64{{P1: 2, P2: 0}: 3, {P1: 2, P2: 1}: 2, {P1: 1, P2: 2}: 1})
+	value, line = TurinMinimaxObjective(defaultTournamentInfo, gameState, isP1PickNext, leaguePoints)
+	if !draftIsComplete(defaultTournamentInfo, line) || !(math.Abs(value-ObjectiveValue(defaultTournamentInfo, line, leaguePoints)) < epsilon) {
+		t.Errorf("Expected a complete line worth %f league points but got %+v", value, line)
+	}
+
+	// Any spread in the result is penalised, so the risk averse value falls short of the best expected game count.
+	games, _ := TurinMinimaxObjective(defaultTournamentInfo, gameState, isP1PickNext, GameWins)
+	value, line = TurinMinimaxObjective(defaultTournamentInfo, gameState, isP1PickNext, VariancePenalty{Score: GameWins, Penalty: 1})
+	if !(math.Abs(value-ObjectiveValue(defaultTournamentInfo, line, VariancePenalty{Score: GameWins, Penalty: 1})) < epsilon) || !(value < games) {
+		t.Errorf("Expected a risk averse line worth less than %f games but got %f along %+v", games, value, line)
+	}
+}
@@ -0,0 +1,213 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"time"
+)
+
+/**
+OpponentModel is what scouting tells us about how the opponent drafts. The opponent is P2 unless IsP1 is set, so that
+either seat can be scouted.
+
+The chance of the opponent making a move is proportional to the weight of the factions it picks, so {KH: 3} means the
+opponent reaches for Khorne three times as readily as anything else. Bans, counter bans and maps are assumed to be
+chosen uniformly at random, other than global bans, which StepPriors can weight.
+*/
+type OpponentModel struct {
+	// IsP1 says the model describes P1 rather than P2.
+	IsP1 bool
+	// ComfortPicks weights factions the opponent likes to play. Unlisted factions weigh 1.
+	ComfortPicks map[Faction]float64
+	// StepPriors overrides ComfortPicks for particular steps, e.g. what the opponent likes to offer as initial picks.
+	StepPriors map[DraftStep]map[Faction]float64
+}
+
+/**
+Like TurinMinimax, except that the opponent the model describes is expected to play like it rather than perfectly.
+Blend says how far to trust the model: at each of the opponent's turns the value is Blend times the expected value of
+its moves under the model, plus 1 - Blend times the value of its best move. Blend 0 is plain minimax, Blend 1 fully
+exploits the model. The value is P1's series win rate either way.
+
+The other player still picks its best move everywhere. Unless Blend is 0 the returned line follows the model's most
+likely move at the opponent's turns. Blind steps are searched as if the counter pick came first. There is no pruning,
+so without a budget this is only practical from the final game on, see ExploitIsTractable and TurinExploitTimed.
+*/
+func TurinExploit(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool, model OpponentModel, blend float64) (float64, GameState) {
+	s := search{tournamentInfo: tournamentInfo}
+	return s.exploit(gameState, isP1PickNext, model, blend, -1)
+}
+
+/**
+TurinExploit searched one pick deeper at a time until either the draft is solved exactly or budget runs out, as in
+TurinMinimaxTimed. Lines cut off are scored by evaluator, which knows nothing of the model. A zero budget searches to
+the end of the draft.
+*/
+func TurinExploitTimed(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool, model OpponentModel, blend float64, budget time.Duration, evaluator Evaluator) (float64, GameState, SearchStats) {
+	start := time.Now()
+	evaluator = evaluatorOrDefault(evaluator)
+	if budget <= 0 {
+		s := search{tournamentInfo: tournamentInfo}
+		value, line := s.exploit(gameState, isP1PickNext, model, blend, -1)
+		stats := s.stats()
+		stats.Elapsed = time.Since(start)
+		return value, line, stats
+	}
+
+	var stats SearchStats
+	bestVal, bestGameState := evaluator(tournamentInfo, gameState), gameState
+	if draftIsComplete(tournamentInfo, gameState) {
+		bestVal = computeWinRate(tournamentInfo, gameState)
+	}
+	deadline := start.Add(budget)
+	for depth := 1; !draftIsComplete(tournamentInfo, gameState); depth++ {
+		s := search{tournamentInfo: tournamentInfo, evaluator: evaluator, deadline: deadline}
+		value, candidateGameState := s.exploit(gameState, isP1PickNext, model, blend, depth)
+		stats = stats.Add(s.stats())
+		if s.timedOut {
+			break
+		}
+		bestVal, bestGameState = value, candidateGameState
+		if !s.horizonHit {
+			break
+		}
+	}
+	stats.Elapsed = time.Since(start)
+	return bestVal, bestGameState, stats
+}
+
+/**
+Whether TurinExploit can search gameState to the end without a budget in reasonable time, which it only can from the
+final game on.
+*/
+func ExploitIsTractable(tournamentInfo TournamentInfo, gameState GameState) bool {
+	return draftIsComplete(tournamentInfo, gameState) || isFinalRound(tournamentInfo, gameState)
+}
+
+/**
+A negative depth searches to the end of the draft, as in minimax.
+*/
+func (s *search) exploit(gameState GameState, isP1PickNext bool, model OpponentModel, blend float64, depth int) (float64, GameState) {
+	tournamentInfo := s.tournamentInfo
+	s.nodes++
+	s.maxDepth = int(math.Max(float64(s.maxDepth), float64(s.ply)))
+	if draftIsComplete(tournamentInfo, gameState) {
+		s.leaves++
+		return computeWinRate(tournamentInfo, gameState), gameState
+	}
+	if depth == 0 {
+		s.horizonHit = true
+		s.leaves++
+		return s.evaluator(tournamentInfo, gameState), gameState
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.timedOut = true
+		s.leaves++
+		return s.evaluator(tournamentInfo, gameState), gameState
+	}
+
+	s.ply++
+	defer func() { s.ply-- }()
+	if resultIsNext(tournamentInfo, gameState) {
+		// Results aren't picks, so they don't count against depth.
+		return expectOverResult(tournamentInfo, gameState, func(v GameState) (float64, GameState) {
+			return s.exploit(v, IsP1PickNext(tournamentInfo, v), model, blend, depth)
+		})
+	}
+
+	successors := getSuccessors(tournamentInfo, gameState)
+	isModelled := isP1PickNext == model.IsP1
+	step := NextStep(tournamentInfo, gameState)
+	bestVal := math.Inf(1)
+	if isP1PickNext {
+		bestVal = math.Inf(-1)
+	}
+	var bestGameState GameState
+	expectedVal := 0.0
+	totalWeight := 0.0
+	likeliestWeight := -1.0
+	var likeliestGameState GameState
+	for _, v := range successors {
+		value, candidateGameState := s.exploit(v, IsP1PickNext(tournamentInfo, v), model, blend, depth-1)
+		if isBetterFor(isP1PickNext, value, bestVal) {
+			bestVal = value
+			bestGameState = candidateGameState
+		}
+		if !isModelled {
+			continue
+		}
+		weight := model.moveWeight(tournamentInfo, step, v)
+		expectedVal += weight * value
+		totalWeight += weight
+		if weight > likeliestWeight {
+			likeliestWeight = weight
+			likeliestGameState = candidateGameState
+		}
+	}
+	if !isModelled || blend == 0 {
+		return bestVal, bestGameState
+	}
+	if totalWeight > 0 {
+		expectedVal /= totalWeight
+	} else {
+		// The model rules out every legal move, so it has nothing to say here.
+		expectedVal = bestVal
+	}
+	return blend*expectedVal + (1.0-blend)*bestVal, likeliestGameState
+}
+
+/**
+How readily the opponent makes the move that produced gameState. Only relative sizes matter.
+*/
+func (m OpponentModel) moveWeight(tournamentInfo TournamentInfo, step DraftStep, gameState GameState) float64 {
+	switch step {
+	case InitialPicks:
+		weight := 1.0
+		for _, v := range gameState.P2Rounds[len(gameState.P2Rounds)-1].Picks {
+			weight *= m.factionWeight(step, v)
+		}
+		return weight
+	case LastInitialPicks:
+		weight := 1.0
+		for _, v := range gameState.P3Round.Picks {
+			weight *= m.factionWeight(step, v)
+		}
+		return weight
+	case MapBan, MapPick:
+		return 1.0
+	case GlobalBan:
+		bans := gameState.P2Bans
+		if m.IsP1 {
+			bans = gameState.P1Bans
+		}
+		if weight, ok := m.StepPriors[step][bans[len(bans)-1]]; ok {
+			return weight
+		}
+		return 1.0
+	default:
+		return m.factionWeight(step, currentMatchupPick(tournamentInfo, gameState, m.IsP1))
+	}
+}
+
+func (m OpponentModel) factionWeight(step DraftStep, faction Faction) float64 {
+	if weight, ok := m.StepPriors[step][faction]; ok {
+		return weight
+	}
+	if weight, ok := m.ComfortPicks[faction]; ok {
+		return weight
+	}
+	return 1.0
+}
+
+// ExploitSolver plays against Model with TurinExploitTimed. A zero Budget searches to the end.
+type ExploitSolver struct {
+	Model     OpponentModel
+	Blend     float64
+	Budget    time.Duration
+	Evaluator Evaluator
+}
+
+func (s ExploitSolver) Solve(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool) (float64, GameState) {
+	value, line, _ := TurinExploitTimed(tournamentInfo, gameState, isP1PickNext, s.Model, s.Blend, s.Budget, s.Evaluator)
+	return value, line
+}
@@ -0,0 +1,142 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+	"time"
+)
+
+var finalRoundPosition = GameState{
+	P2Rounds: []P2Round{
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+		{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+	},
+	P3Round: P3Round{Picks: []Faction{GC, KI, NG}, Ban: SL},
+}
+
+func TestExploitNoBlendIsMinimax(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{ComfortPicks: map[Faction]float64{TZ: 10.0, NG: 4.0}}
+
+	for _, gameState := range []GameState{bo3Positions[2], bo3Positions[3], finalRoundPosition} {
+		isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
+		expected, _ := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+		actual, _ := TurinExploit(tournamentInfo, gameState, isP1PickNext, model, 0.0)
+		if !(math.Abs(actual-expected) < epsilon) {
+			t.Errorf("Expected WR for %+v to be %f but it was %f", gameState, expected, actual)
+		}
+	}
+}
+
+func TestExploitGainsWithBlend(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{ComfortPicks: map[Faction]float64{TZ: 10.0}}
+	gameState := bo3Positions[2]
+
+	previous := -1.0
+	for _, blend := range []float64{0.0, .5, 1.0} {
+		value, _ := TurinExploit(tournamentInfo, gameState, true, model, blend)
+		if value < previous-epsilon {
+			t.Errorf("Expected trusting the model more to never lower WR but %f went to %f at blend %f", previous, value, blend)
+		}
+		previous = value
+	}
+}
+
+func TestExploitP1NoBlendIsMinimax(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{IsP1: true, ComfortPicks: map[Faction]float64{TZ: 10.0, NG: 4.0}}
+
+	for _, gameState := range []GameState{bo3Positions[2], bo3Positions[3], finalRoundPosition} {
+		isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
+		expected, _ := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+		actual, _ := TurinExploit(tournamentInfo, gameState, isP1PickNext, model, 0.0)
+		if !(math.Abs(actual-expected) < epsilon) {
+			t.Errorf("Expected WR for %+v to be %f but it was %f", gameState, expected, actual)
+		}
+	}
+}
+
+func TestExploitP1LosesWithBlend(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{IsP1: true, ComfortPicks: map[Faction]float64{TZ: 10.0}}
+	gameState := bo3Positions[2]
+
+	// Trusting a model of P1 more means P2 gets to exploit it more, which can only lower P1's WR.
+	previous := 2.0
+	for _, blend := range []float64{0.0, .5, 1.0} {
+		value, _ := TurinExploit(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), model, blend)
+		if value > previous+epsilon {
+			t.Errorf("Expected trusting the model of P1 more to never raise its WR but %f went to %f at blend %f", previous, value, blend)
+		}
+		previous = value
+	}
+}
+
+func TestExploitPunishesComfortPick(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{ComfortPicks: map[Faction]float64{TZ: 100.0}}
+
+	_, line := TurinExploit(tournamentInfo, finalRoundPosition, false, model, 1.0)
+	if line.P3Round.Matchup.P2 != TZ {
+		t.Fatalf("Expected the line to follow P2's comfort pick TZ but got %+v", line)
+	}
+
+	// Knowing TZ is coming, P1 should play whichever remaining initial pick does best against it.
+	bestVal := -1.0
+	for _, v := range line.P3Round.Picks {
+		if v == line.P3Round.CounterBan {
+			continue
+		}
+		candidate := deepcopy(line)
+		candidate.P3Round.Matchup.P1 = v
+		bestVal = math.Max(bestVal, computeWinRate(tournamentInfo, candidate))
+	}
+	actual := computeWinRate(tournamentInfo, line)
+	if !(math.Abs(actual-bestVal) < epsilon) {
+		t.Errorf("Expected P1's final pick to be worth %f against TZ but it was worth %f", bestVal, actual)
+	}
+}
+
+func TestExploitStepPriorOverridesComfortPick(t *testing.T) {
+	model := OpponentModel{
+		ComfortPicks: map[Faction]float64{TZ: 5.0},
+		StepPriors:   map[DraftStep]map[Faction]float64{LastCounterPick: {TZ: .5}},
+	}
+
+	if weight := model.factionWeight(LastCounterPick, TZ); weight != .5 {
+		t.Errorf("Expected the step prior of .5 but got %f", weight)
+	}
+	if weight := model.factionWeight(CounterPick, TZ); weight != 5.0 {
+		t.Errorf("Expected the comfort weight of 5 but got %f", weight)
+	}
+	if weight := model.factionWeight(CounterPick, KH); weight != 1.0 {
+		t.Errorf("Expected unlisted factions to weigh 1 but got %f", weight)
+	}
+}
+
+func TestExploitTimed(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	model := OpponentModel{ComfortPicks: map[Faction]float64{TZ: 10.0}}
+	budget := 200 * time.Millisecond
+
+	// Searching a Bo3 against a model from the start takes minutes without pruning.
+	value, _, stats := TurinExploitTimed(tournamentInfo, GameState{}, true, model, 1.0, budget, nil)
+	if stats.Elapsed > 10*budget {
+		t.Errorf("Expected the search to keep to its %s budget but it took %s", budget, stats.Elapsed)
+	}
+	if !(value >= 0 && value <= 1) {
+		t.Errorf("Expected a win rate but got %f", value)
+	}
+
+	// With time to spare the final game is solved exactly.
+	expected, _ := TurinExploit(tournamentInfo, finalRoundPosition, false, model, 1.0)
+	actual, line, _ := TurinExploitTimed(tournamentInfo, finalRoundPosition, false, model, 1.0, time.Minute, nil)
+	if !(math.Abs(actual-expected) < epsilon) || !IsDraftComplete(tournamentInfo, line) {
+		t.Errorf("Expected the final game to be solved as %f but got %f and %+v", expected, actual, line)
+	}
+	if ExploitIsTractable(tournamentInfo, GameState{}) || !ExploitIsTractable(tournamentInfo, finalRoundPosition) {
+		t.Errorf("Expected only the final game to be tractable without a budget")
+	}
+}
@@ -0,0 +1,211 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"time"
+)
+
+const reviewTolerance = 1e-9
+
+// MoveReview grades one decision of a draft against the best decision available at the time.
+type MoveReview struct {
+	Step DraftStep
+	// Round is the zero based game the move was made in.
+	Round int
+	IsP1  bool
+	// Played and Best are the draft right after the move that was made and after the best one.
+	Played GameState
+	Best   GameState
+	// P1's series win rate after each move, assuming perfect play from there on.
+	PlayedValue float64
+	BestValue   float64
+	// WinRateLost is how much series win rate the mover gave up, from their own point of view.
+	WinRateLost float64
+	// Accuracy is 1 for the best move and 0 for the worst one, scaled linearly by value in between.
+	Accuracy  float64
+	IsMistake bool
+}
+
+// DraftReview is the post-mortem of a draft, move by move and summed up per player.
+type DraftReview struct {
+	Moves         []MoveReview
+	P1Accuracy    float64
+	P2Accuracy    float64
+	P1WinRateLost float64
+	P2WinRateLost float64
+	P1Mistakes    int
+	P2Mistakes    int
+}
+
+/**
+Replays the draft that led to gameState one decision at a time, like a chess engine's game review. Every legal move at
+each decision is valued with TurinMinimax, and the move that was made is compared against the best of them. Moves that
+give up more than threshold of series win rate are flagged as mistakes.
+
+gameState is normally a finished draft. If it is not, the review stops at the last decision it records. Blind steps
+are graded as if the counter pick had been revealed first.
+*/
+func ReviewDraft(tournamentInfo TournamentInfo, gameState GameState, threshold float64) DraftReview {
+	review, _ := SearchReview(tournamentInfo, gameState, threshold, SearchOptions{})
+	return review
+}
+
+// reviewDecision is one decision of the draft under review, with every move that could have been made there.
+type reviewDecision struct {
+	current     GameState
+	successors  []GameState
+	playedIndex int
+	values      []float64
+}
+
+/**
+ReviewDraft, valuing every move with Search and options, and reporting the work all those searches took. Reviews are
+always of the series win rate, so options' Objective is ignored.
+
+A budget in options is for the whole review rather than each search, and is shared out evenly between the moves still
+to be valued. The last decisions are valued first, as they are the quickest to solve exactly, and a table in options
+carries what they solve over to the decisions before them.
+*/
+func SearchReview(tournamentInfo TournamentInfo, gameState GameState, threshold float64, options SearchOptions) (DraftReview, SearchStats) {
+	start := time.Now()
+	options.Objective = nil
+
+	var decisions []reviewDecision
+	searches := 0
+	current := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}
+	for !draftIsComplete(tournamentInfo, current) {
+		if resultIsNext(tournamentInfo, current) {
+			// Results recorded in gameState have been carried over already, so this one is missing and nothing after it
+			// can be replayed.
+			break
+		}
+		successors := getSuccessors(tournamentInfo, current)
+		playedIndex := -1
+		for i, v := range successors {
+			copyResults(gameState, v)
+			if playedIndex == -1 && isAncestor(v, gameState) {
+				playedIndex = i
+			}
+		}
+		if playedIndex == -1 {
+			break
+		}
+		decisions = append(decisions, reviewDecision{current: current, successors: successors, playedIndex: playedIndex})
+		searches += len(successors)
+		current = successors[playedIndex]
+	}
+
+	var stats SearchStats
+	deadline := start.Add(options.Budget)
+	for i := len(decisions) - 1; i >= 0; i-- {
+		decisions[i].values = make([]float64, len(decisions[i].successors))
+		for j, v := range decisions[i].successors {
+			searchOptions := options
+			if options.Budget > 0 {
+				// A spent budget still has to be positive, or the search would have no limit at all.
+				searchOptions.Budget = time.Duration(math.Max(float64(time.Until(deadline))/float64(searches), 1))
+			}
+			var searchStats SearchStats
+			decisions[i].values[j], _, searchStats = Search(tournamentInfo, v, IsP1PickNext(tournamentInfo, v), searchOptions)
+			stats = stats.Add(searchStats)
+			searches--
+		}
+	}
+
+	var review DraftReview
+	var p1AccuracyTotal, p2AccuracyTotal float64
+	var p1Moves, p2Moves int
+	for _, decision := range decisions {
+		current, successors, playedIndex, values := decision.current, decision.successors, decision.playedIndex, decision.values
+		isP1 := IsP1PickNext(tournamentInfo, current)
+		step := NextStep(tournamentInfo, current)
+
+		bestIndex, worstIndex := 0, 0
+		for i, v := range values {
+			if isBetterFor(isP1, v, values[bestIndex]) {
+				bestIndex = i
+			}
+			if isBetterFor(isP1, values[worstIndex], v) {
+				worstIndex = i
+			}
+		}
+
+		move := MoveReview{
+			Step:        step,
+			Round:       moveRound(tournamentInfo, current, successors[playedIndex]),
+			IsP1:        isP1,
+			Played:      successors[playedIndex],
+			Best:        successors[bestIndex],
+			PlayedValue: values[playedIndex],
+			BestValue:   values[bestIndex],
+			Accuracy:    1.0,
+		}
+		move.WinRateLost = values[bestIndex] - values[playedIndex]
+		if !isP1 {
+			move.WinRateLost = -move.WinRateLost
+		}
+		spread := values[bestIndex] - values[worstIndex]
+		if spread != 0 {
+			move.Accuracy = (values[playedIndex] - values[worstIndex]) / spread
+		}
+		// Allow for rounding so that losing exactly threshold isn't flagged.
+		move.IsMistake = move.WinRateLost > threshold+reviewTolerance
+
+		review.Moves = append(review.Moves, move)
+		if isP1 {
+			p1Moves++
+			p1AccuracyTotal += move.Accuracy
+			review.P1WinRateLost += move.WinRateLost
+			if move.IsMistake {
+				review.P1Mistakes++
+			}
+		} else {
+			p2Moves++
+			p2AccuracyTotal += move.Accuracy
+			review.P2WinRateLost += move.WinRateLost
+			if move.IsMistake {
+				review.P2Mistakes++
+			}
+		}
+	}
+
+	if p1Moves > 0 {
+		review.P1Accuracy = p1AccuracyTotal / float64(p1Moves)
+	}
+	if p2Moves > 0 {
+		review.P2Accuracy = p2AccuracyTotal / float64(p2Moves)
+	}
+	stats.Elapsed = time.Since(start)
+	return review, stats
+}
+
+/**
+The game a move from before to after was made in. Map bans come before the first game and count towards it.
+*/
+func moveRound(tournamentInfo TournamentInfo, before GameState, after GameState) int {
+	if isFinalRound(tournamentInfo, before) || len(after.P2Rounds) == 0 {
+		return len(before.P2Rounds)
+	}
+	return len(after.P2Rounds) - 1
+}
+
+func isBetterFor(isP1 bool, value float64, than float64) bool {
+	if isP1 {
+		return value > than
+	}
+	return value < than
+}
+
+/**
+Game results decide who picks first in the final game, and depending on the ruleset in others too, so carry over the
+ones recorded in from. Only games to has finished drafting get theirs, as a result counted any earlier can decide the
+series while its game is still being drafted.
+*/
+func copyResults(from GameState, to GameState) {
+	for i, v := range to.P2Rounds {
+		if i < len(from.P2Rounds) && v.Matchup.P1 != EMPTY && v.Matchup.P2 != EMPTY {
+			to.P2Rounds[i].WhoWon = from.P2Rounds[i].WhoWon
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReviewDraftPerfectLine(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	_, gameState := TurinMinimax(tournamentInfo, GameState{}, true, -1.0, 2.0)
+
+	review := ReviewDraft(tournamentInfo, gameState, .01)
+
+	// Three moves per game.
+	expectedMoves := 9
+	if len(review.Moves) != expectedMoves {
+		t.Fatalf("Expected %d moves but got %d", expectedMoves, len(review.Moves))
+	}
+	if review.P1Mistakes != 0 || review.P2Mistakes != 0 {
+		t.Errorf("Expected no mistakes in the minimax line but got %+v", review)
+	}
+	if !(math.Abs(review.P1WinRateLost) < epsilon) || !(math.Abs(review.P2WinRateLost) < epsilon) {
+		t.Errorf("Expected no win rate lost but got %f and %f", review.P1WinRateLost, review.P2WinRateLost)
+	}
+	if review.P1Accuracy != 1.0 || review.P2Accuracy != 1.0 {
+		t.Errorf("Expected perfect accuracy but got %f and %f", review.P1Accuracy, review.P2Accuracy)
+	}
+
+	expectedSteps := []DraftStep{InitialPicks, CounterPick, FinalPick, InitialPicks, CounterPick, FinalPick,
+		LastInitialPicks, LastCounterPick, LastFinalPick}
+	expectedRounds := []int{0, 0, 0, 1, 1, 1, 2, 2, 2}
+	expectedIsP1 := []bool{true, false, true, false, true, false, true, false, true}
+	for i, v := range review.Moves {
+		if v.Step != expectedSteps[i] || v.Round != expectedRounds[i] || v.IsP1 != expectedIsP1[i] {
+			t.Errorf("Expected move %d to be %s by P1: %t in round %d but got %+v", i, expectedSteps[i], expectedIsP1[i], expectedRounds[i], v)
+		}
+	}
+}
+
+func TestReviewDraftFindsBlunder(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: GC}},
+			{Picks: []Faction{KH, KI}, Matchup: Matchup{P1: KH, P2: KH}},
+		},
+		P3Round: P3Round{
+			Picks:      []Faction{KI, NG, OK},
+			Ban:        KI,
+			CounterBan: KI,
+			// OK loses to SL and TZ for certain, NG would have been an even game.
+			Matchup: Matchup{P1: OK, P2: NG}},
+	}
+
+	review := ReviewDraft(tournamentInfo, gameState, .1)
+
+	last := review.Moves[len(review.Moves)-1]
+	if last.Step != LastFinalPick || !last.IsP1 || !last.IsMistake {
+		t.Fatalf("Expected P1's final pick to be flagged but got %+v", last)
+	}
+	if last.Best.P3Round.Matchup.P1 != NG {
+		t.Errorf("Expected NG to be the best final pick but got %+v", last.Best.P3Round)
+	}
+	expectedLost := computeWinRate(tournamentInfo, last.Best) - computeWinRate(tournamentInfo, gameState)
+	if !(math.Abs(last.WinRateLost-expectedLost) < epsilon) {
+		t.Errorf("Expected %f WR lost but got %f", expectedLost, last.WinRateLost)
+	}
+	if review.P1Mistakes < 1 || review.P1WinRateLost < expectedLost-epsilon || review.P1Accuracy >= 1.0 {
+		t.Errorf("Expected P1's totals to include the blunder but got %+v", review)
+	}
+}
+
+func TestReviewDraftPartial(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: EMPTY, P2: GC}},
+		},
+		P3Round: P3Round{},
+	}
+
+	review := ReviewDraft(tournamentInfo, gameState, .1)
+
+	expectedMoves := 2
+	if len(review.Moves) != expectedMoves {
+		t.Errorf("Expected %d moves but got %d", expectedMoves, len(review.Moves))
+	}
+}
+
+func TestSearchReviewSharesTable(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: matchupsPolarized}
+	_, gameState := TurinMinimax(tournamentInfo, GameState{}, true, -1.0, 2.0)
+
+	expected := ReviewDraft(tournamentInfo, gameState, .01)
+	actual, stats := SearchReview(tournamentInfo, gameState, .01, SearchOptions{Table: NewTranspositionTable()})
+
+	if len(actual.Moves) != len(expected.Moves) {
+		t.Fatalf("Expected %d moves but got %d", len(expected.Moves), len(actual.Moves))
+	}
+	for i, v := range actual.Moves {
+		if !(math.Abs(v.PlayedValue-expected.Moves[i].PlayedValue) < epsilon) || !(math.Abs(v.BestValue-expected.Moves[i].BestValue) < epsilon) {
+			t.Errorf("Expected move %d to be valued as without a table but got %+v", i, v)
+		}
+	}
+	if stats.Nodes == 0 || stats.TranspositionHits == 0 {
+		t.Errorf("Expected the searches to count their nodes and reuse each other's positions but got %+v", stats)
+	}
+}
+
+func TestSearchReviewBudget(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{P2Rounds: []P2Round{{Picks: []Faction{SL, TZ}}}}
+	budget := 200 * time.Millisecond
+
+	review, stats := SearchReview(tournamentInfo, gameState, .05, SearchOptions{Budget: budget})
+
+	if len(review.Moves) != 1 {
+		t.Fatalf("Expected the opening move to be reviewed but got %d moves", len(review.Moves))
+	}
+	// Searching every opening of a Bo5 to the end takes minutes.
+	if stats.Elapsed > 10*budget {
+		t.Errorf("Expected the review to keep to its %s budget but it took %s", budget, stats.Elapsed)
+	}
+}
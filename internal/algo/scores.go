@@ -0,0 +1,130 @@
+package algo
+
+import (
+	"fmt"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"sort"
+	"time"
+)
+
+// Score is a series' final score in games, e.g. 2-1 to P1.
+type Score struct {
+	P1 int
+	P2 int
+}
+
+func (s Score) String() string {
+	return fmt.Sprintf("%d-%d", s.P1, s.P2)
+}
+
+// ScoreOdds is the chance of a series ending in Score.
+type ScoreOdds struct {
+	Score Score
+	Odds  float64
+}
+
+/**
+The odds of each final score of a complete draft, best for P1 first. The series stops as soon as a player has won a
+majority of its games, so a Bo3 ends 2-0, 2-1, 1-2 or 0-2. Recorded results are taken as certain.
+*/
+func ScoreDistribution(tournamentInfo TournamentInfo, gameState GameState) []ScoreOdds {
+	needed := tournamentInfo.RoundCount/2 + 1
+	odds := map[Score]float64{}
+	var playOut func(game int, score Score, probability float64)
+	playOut = func(game int, score Score, probability float64) {
+		// Games after a certain loss are never reached, and may not have been drafted.
+		if probability == 0 {
+			return
+		}
+		if score.P1 == needed || score.P2 == needed {
+			odds[score] += probability
+			return
+		}
+		var p1Odds float64
+		if game < len(gameState.P2Rounds) {
+			p1Odds = roundOdds(tournamentInfo, gameState.P2Rounds[game])
+		} else {
+			p1Odds = GetMatchupValue(gameState.P3Round.Matchup, tournamentInfo)
+		}
+		playOut(game+1, Score{P1: score.P1 + 1, P2: score.P2}, probability*p1Odds)
+		playOut(game+1, Score{P1: score.P1, P2: score.P2 + 1}, probability*(1.0-p1Odds))
+	}
+	playOut(0, Score{}, 1.0)
+	return sortedScoreOdds(odds)
+}
+
+/**
+The odds of each final score when both players draft perfectly from gameState on, as found by TurinMinimaxObjective, or
+by TurinMinimax if objective is nil. Where the draft waits on a game's result, both results are solved and weighted by
+the odds of the game.
+*/
+func SolveScoreDistribution(tournamentInfo TournamentInfo, gameState GameState, objective Objective) []ScoreOdds {
+	scoreOdds, _ := SearchScoreDistribution(tournamentInfo, gameState, SearchOptions{Objective: objective})
+	return scoreOdds
+}
+
+/**
+Like SolveScoreDistribution, for options' objective and with its table, and reports how much work the searches took.
+The odds always come from searching to the end, whatever options' budget.
+*/
+func SearchScoreDistribution(tournamentInfo TournamentInfo, gameState GameState, options SearchOptions) ([]ScoreOdds, SearchStats) {
+	start := time.Now()
+	s := search{tournamentInfo: tournamentInfo, objective: options.Objective, table: options.Table}
+	scoreOdds := s.scoreDistribution(gameState)
+	stats := s.stats()
+	stats.Elapsed = time.Since(start)
+	return scoreOdds, stats
+}
+
+func (s *search) scoreDistribution(gameState GameState) []ScoreOdds {
+	tournamentInfo := s.tournamentInfo
+	if draftIsComplete(tournamentInfo, gameState) {
+		return ScoreDistribution(tournamentInfo, gameState)
+	}
+	if resultIsNext(tournamentInfo, gameState) {
+		p1Odds := resultOdds(tournamentInfo, gameState)
+		odds := map[Score]float64{}
+		for i, v := range getSuccessorsResult(gameState) {
+			weight := p1Odds
+			if i == 1 {
+				weight = 1.0 - p1Odds
+			}
+			for _, scoreOdds := range s.scoreDistribution(v) {
+				odds[scoreOdds.Score] += weight * scoreOdds.Odds
+			}
+		}
+		return sortedScoreOdds(odds)
+	}
+
+	low, high := s.bounds()
+	_, line := s.minimax(gameState, IsP1PickNext(tournamentInfo, gameState), low-1.0, high+1.0, -1)
+	// The line follows the likelier result wherever the search averaged over both, so pick it up again from the first
+	// of those.
+	for i := range line.P2Rounds {
+		if i < len(gameState.P2Rounds) && gameState.P2Rounds[i].WhoWon != NoOneYet {
+			continue
+		}
+		if line.P2Rounds[i].WhoWon != NoOneYet {
+			waiting := deepcopy(line)
+			waiting.P2Rounds = waiting.P2Rounds[:i+1]
+			waiting.P2Rounds[i].WhoWon = NoOneYet
+			waiting.P3Round = P3Round{}
+			return s.scoreDistribution(waiting)
+		}
+	}
+	return ScoreDistribution(tournamentInfo, line)
+}
+
+/**
+Scores from P1's biggest win to their biggest loss.
+*/
+func sortedScoreOdds(odds map[Score]float64) []ScoreOdds {
+	var sorted []ScoreOdds
+	for k, v := range odds {
+		sorted = append(sorted, ScoreOdds{Score: k, Odds: v})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Score.P1-sorted[i].Score.P2 > sorted[j].Score.P1-sorted[j].Score.P2
+	})
+	return sorted
+}
@@ -0,0 +1,129 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+)
+
+func TestScoreDistribution(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: KI}},
+			{Picks: []Faction{NG, SL}, Matchup: Matchup{P1: KH, P2: NG}},
+		},
+		P3Round: P3Round{Picks: []Faction{KI, NG, OK}, Ban: TZ, CounterBan: KI, Matchup: Matchup{P1: OK, P2: TZ}},
+	}
+	a, b, c := .55, .4, .4
+	expected := map[Score]float64{
+		{P1: 2, P2: 0}: a * b,
+		{P1: 2, P2: 1}: a*(1-b)*c + (1-a)*b*c,
+		{P1: 1, P2: 2}: a*(1-b)*(1-c) + (1-a)*b*(1-c),
+		{P1: 0, P2: 2}: (1 - a) * (1 - b),
+	}
+
+	actual := ScoreDistribution(defaultTournamentInfo, gameState)
+	if len(actual) != len(expected) || actual[0].Score != (Score{P1: 2, P2: 0}) || actual[3].Score != (Score{P1: 0, P2: 2}) {
+		t.Errorf("Expected every Bo3 score from 2-0 down to 0-2 but got %v", actual)
+	}
+	p1Wins := 0.0
+	for _, v := range actual {
+		if !(math.Abs(v.Odds-expected[v.Score]) < epsilon) {
+			t.Errorf("Expected %s to have odds %f but got %f", v.Score, expected[v.Score], v.Odds)
+		}
+		if v.Score.P1 > v.Score.P2 {
+			p1Wins += v.Odds
+		}
+	}
+	if winRate := computeWinRate(defaultTournamentInfo, gameState); !(math.Abs(p1Wins-winRate) < epsilon) {
+		t.Errorf("Expected P1's scores to add up to their win rate of %f but got %f", winRate, p1Wins)
+	}
+
+	// Once P1 is 2-0 up the last game doesn't matter.
+	gameState.P2Rounds[0].WhoWon = P1
+	gameState.P2Rounds[1].WhoWon = P1
+	if actual := ScoreDistribution(defaultTournamentInfo, gameState); len(actual) != 1 || actual[0].Odds != 1.0 {
+		t.Errorf("Expected a certain 2-0 but got %v", actual)
+	}
+}
+
+func TestStopWhenDecided(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{StopWhenDecided: true}}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: KI}, WhoWon: P1},
+			{Picks: []Faction{NG, SL}, Matchup: Matchup{P1: KH, P2: NG}},
+		},
+		P3Round: P3Round{},
+	}
+	p := GetMatchupValue(Matchup{P1: KH, P2: NG}, tournamentInfo)
+
+	// Winning the second game ends the series, so the final game only gets drafted after a loss.
+	results := getSuccessors(tournamentInfo, gameState)
+	if !draftIsComplete(tournamentInfo, results[0]) || draftIsComplete(tournamentInfo, results[1]) {
+		t.Errorf("Expected only a P2 win to leave the series live but got %+v", results)
+	}
+	finalGame, _ := TurinMinimax(tournamentInfo, results[1], IsP1PickNext(tournamentInfo, results[1]), -1.0, 2.0)
+
+	value, _ := TurinMinimax(tournamentInfo, gameState, false, -1.0, 2.0)
+	if expected := p + (1-p)*finalGame; !(math.Abs(value-expected) < epsilon) {
+		t.Errorf("Expected the series to be worth %f but got %f", expected, value)
+	}
+
+	scores := SolveScoreDistribution(tournamentInfo, gameState, nil)
+	expected := []ScoreOdds{
+		{Score: Score{P1: 2, P2: 0}, Odds: p},
+		{Score: Score{P1: 2, P2: 1}, Odds: (1 - p) * finalGame},
+		{Score: Score{P1: 1, P2: 2}, Odds: (1 - p) * (1 - finalGame)},
+	}
+	if len(scores) != len(expected) {
+		t.Fatalf("Expected scores %v but got %v", expected, scores)
+	}
+	for i, v := range expected {
+		if scores[i].Score != v.Score || !(math.Abs(scores[i].Odds-v.Odds) < epsilon) {
+			t.Errorf("Expected %s to have odds %f but got %v", v.Score, v.Odds, scores[i])
+		}
+	}
+
+	// Before P2's final pick, the scores come from picking the best line back up at the result it waits on.
+	earlier := deepcopy(gameState)
+	earlier.P2Rounds[1].Matchup.P2 = EMPTY
+	value, _ = TurinMinimax(tournamentInfo, earlier, false, -1.0, 2.0)
+	p1Wins := 0.0
+	for _, v := range SolveScoreDistribution(tournamentInfo, earlier, nil) {
+		if v.Score.P1 > v.Score.P2 {
+			p1Wins += v.Odds
+		}
+	}
+	if !(math.Abs(p1Wins-value) < epsilon) {
+		t.Errorf("Expected P1's scores to add up to their win rate of %f but got %f", value, p1Wins)
+	}
+}
+
+func TestSearchScoreDistributionSharesTable(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Ruleset{StopWhenDecided: true}}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+		},
+		P3Round: P3Round{},
+	}
+	expected := SolveScoreDistribution(tournamentInfo, gameState, nil)
+
+	table := NewTranspositionTable()
+	Search(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), SearchOptions{Table: table})
+	scores, stats := SearchScoreDistribution(tournamentInfo, gameState, SearchOptions{Table: table})
+
+	if len(scores) != len(expected) {
+		t.Fatalf("Expected scores %v but got %v", expected, scores)
+	}
+	for i, v := range expected {
+		if scores[i].Score != v.Score || !(math.Abs(scores[i].Odds-v.Odds) < epsilon) {
+			t.Errorf("Expected %s to have odds %f but got %v", v.Score, v.Odds, scores[i])
+		}
+	}
+	// Each result the draft waits on was solved by the first search.
+	if stats.TranspositionHits == 0 || stats.Nodes != stats.TranspositionHits {
+		t.Errorf("Expected every position to come from the table but got %+v", stats)
+	}
+}
@@ -0,0 +1,199 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"time"
+)
+
+// BlindChoice is one faction in a mixed strategy and how often to play it.
+type BlindChoice struct {
+	Faction     Faction
+	Probability float64
+}
+
+/**
+The equilibrium for a blind counter pick step, see Ruleset.SimultaneousSteps. Neither player can do better than
+picking at random with the given probabilities, and doing so guarantees P1 a series win rate of Value.
+*/
+type SimultaneousSolution struct {
+	Step DraftStep
+	// CounterBan is what the counter picker should announce before the blind picks. Only set in the final game.
+	CounterBan Faction
+	Value      float64
+	P1         []BlindChoice
+	P2         []BlindChoice
+}
+
+/**
+If the draft is waiting on a blind step, returns its equilibrium. The second return value is false when the next step
+is an ordinary one or the draft is already complete.
+*/
+func SolveSimultaneousStep(tournamentInfo TournamentInfo, gameState GameState) (SimultaneousSolution, bool) {
+	solution, ok, _ := SearchSimultaneousStep(tournamentInfo, gameState, SearchOptions{})
+	return solution, ok
+}
+
+/**
+Like SolveSimultaneousStep, for options' objective and with its table, and reports how much work the search took. The
+step is always searched to the end, whatever options' budget.
+*/
+func SearchSimultaneousStep(tournamentInfo TournamentInfo, gameState GameState, options SearchOptions) (SimultaneousSolution, bool, SearchStats) {
+	if draftIsComplete(tournamentInfo, gameState) || !tournamentInfo.Ruleset.IsSimultaneous(NextStep(tournamentInfo, gameState)) {
+		return SimultaneousSolution{}, false, SearchStats{}
+	}
+	start := time.Now()
+	s := search{tournamentInfo: tournamentInfo, objective: options.Objective, table: options.Table}
+	solution, _ := s.simultaneous(gameState, -1)
+	stats := s.stats()
+	stats.Elapsed = time.Since(start)
+	return solution, true, stats
+}
+
+/**
+Solves a blind step as a matrix game between the counter picker's options and the first picker's final pick, valuing
+each pair by searching on from the resulting draft. In the final game the counter ban is made openly first, so the
+counter picker takes whichever counter ban leaves the matrix game best for them.
+
+Returns the equilibrium along with the line through the most likely pair of picks.
+*/
+func (s *search) simultaneous(gameState GameState, depth int) (SimultaneousSolution, GameState) {
+	tournamentInfo := s.tournamentInfo
+	step := NextStep(tournamentInfo, gameState)
+	isP1First := isP1FirstPicker(tournamentInfo, gameState)
+
+	var counterBans []Faction
+	counterPicksByBan := map[Faction][]GameState{}
+	for _, v := range getSuccessors(tournamentInfo, gameState) {
+		counterBan := v.P3Round.CounterBan
+		if _, ok := counterPicksByBan[counterBan]; !ok {
+			counterBans = append(counterBans, counterBan)
+		}
+		counterPicksByBan[counterBan] = append(counterPicksByBan[counterBan], v)
+	}
+
+	var bestSolution SimultaneousSolution
+	var bestGameState GameState
+	for i, counterBan := range counterBans {
+		solution, line := s.blindPicks(counterPicksByBan[counterBan], isP1First, depth)
+		// The counter picker is P1 exactly when P2 picked first.
+		if i == 0 || (!isP1First && solution.Value > bestSolution.Value) || (isP1First && solution.Value < bestSolution.Value) {
+			bestSolution = solution
+			bestSolution.CounterBan = counterBan
+			bestGameState = line
+		}
+	}
+	bestSolution.Step = step
+	return bestSolution, bestGameState
+}
+
+func (s *search) blindPicks(counterPicks []GameState, isP1First bool, depth int) (SimultaneousSolution, GameState) {
+	tournamentInfo := s.tournamentInfo
+	remainingDepth := depth - 2
+	if depth >= 0 && remainingDepth < 0 {
+		remainingDepth = 0
+	}
+
+	// The first picker's options don't depend on the counter pick, so read them off the first one.
+	var finalPicks []Faction
+	for _, v := range getSuccessors(tournamentInfo, counterPicks[0]) {
+		finalPicks = append(finalPicks, currentMatchupPick(tournamentInfo, v, isP1First))
+	}
+	var counterPickFactions []Faction
+	for _, v := range counterPicks {
+		counterPickFactions = append(counterPickFactions, currentMatchupPick(tournamentInfo, v, !isP1First))
+	}
+
+	// values[i][j] is the value of first picker option i against counter pick j.
+	low, high := s.bounds()
+	values := make([][]float64, len(finalPicks))
+	lines := make([][]GameState, len(finalPicks))
+	for i := range finalPicks {
+		values[i] = make([]float64, len(counterPicks))
+		lines[i] = make([]GameState, len(counterPicks))
+	}
+	for j, counterPick := range counterPicks {
+		for _, v := range getSuccessors(tournamentInfo, counterPick) {
+			pick := currentMatchupPick(tournamentInfo, v, isP1First)
+			for i, finalPick := range finalPicks {
+				if finalPick == pick {
+					values[i][j], lines[i][j] = s.minimax(v, IsP1PickNext(tournamentInfo, v), low-1.0, high+1.0, remainingDepth)
+				}
+			}
+		}
+	}
+
+	// The matrix game wants P1 on the rows.
+	payoff := values
+	if !isP1First {
+		payoff = transpose(values)
+	}
+	value, rowStrategy, colStrategy := solveMatrixGame(payoff)
+
+	firstStrategy, counterStrategy := rowStrategy, colStrategy
+	if !isP1First {
+		firstStrategy, counterStrategy = colStrategy, rowStrategy
+	}
+	var bestGameState GameState
+	bestProbability := -1.0
+	for i := range finalPicks {
+		for j := range counterPicks {
+			if firstStrategy[i]*counterStrategy[j] > bestProbability {
+				bestProbability = firstStrategy[i] * counterStrategy[j]
+				bestGameState = lines[i][j]
+			}
+		}
+	}
+
+	solution := SimultaneousSolution{Value: value}
+	firstChoices := toBlindChoices(finalPicks, firstStrategy)
+	counterChoices := toBlindChoices(counterPickFactions, counterStrategy)
+	if isP1First {
+		solution.P1, solution.P2 = firstChoices, counterChoices
+	} else {
+		solution.P1, solution.P2 = counterChoices, firstChoices
+	}
+	return solution, bestGameState
+}
+
+/**
+Whether P1 made, or is about to make, the initial picks of the game currently being drafted.
+*/
+func isP1FirstPicker(tournamentInfo TournamentInfo, gameState GameState) bool {
+	if isFinalRound(tournamentInfo, gameState) || NextStep(tournamentInfo, gameState) == InitialPicks {
+		return isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds))
+	}
+	return isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds)-1)
+}
+
+/**
+The faction P1 or P2 has picked to play in the game currently being drafted.
+*/
+func currentMatchupPick(tournamentInfo TournamentInfo, gameState GameState, isP1 bool) Faction {
+	matchup := gameState.P3Round.Matchup
+	if len(gameState.P2Rounds) < tournamentInfo.RoundCount-1 || len(gameState.P3Round.Picks) == 0 {
+		matchup = gameState.P2Rounds[len(gameState.P2Rounds)-1].Matchup
+	}
+	if isP1 {
+		return matchup.P1
+	}
+	return matchup.P2
+}
+
+func toBlindChoices(factions []Faction, strategy []float64) []BlindChoice {
+	var choices []BlindChoice
+	for i, v := range factions {
+		choices = append(choices, BlindChoice{Faction: v, Probability: strategy[i]})
+	}
+	return choices
+}
+
+func transpose(matrix [][]float64) [][]float64 {
+	transposed := make([][]float64, len(matrix[0]))
+	for j := range transposed {
+		transposed[j] = make([]float64, len(matrix))
+		for i := range matrix {
+			transposed[j][i] = matrix[i][j]
+		}
+	}
+	return transposed
+}
@@ -0,0 +1,150 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+)
+
+func TestSolveSimultaneousStepFinalRound(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2Blind}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+		},
+		P3Round: P3Round{Picks: []Faction{GC, KI, NG}, Ban: SL},
+	}
+
+	solution, ok := SolveSimultaneousStep(tournamentInfo, gameState)
+	if !ok {
+		t.Fatalf("Expected %+v to be waiting on a blind step", gameState)
+	}
+	if solution.Step != LastCounterPick {
+		t.Errorf("Expected step %s but got %s", LastCounterPick, solution.Step)
+	}
+
+	// Rebuild the matrix game for the announced counter ban and check the strategies against it.
+	var p1Options []Faction
+	for _, v := range gameState.P3Round.Picks {
+		if v != solution.CounterBan {
+			p1Options = append(p1Options, v)
+		}
+	}
+	var p2Options []Faction
+	for _, v := range getRemainingPicks(tournamentInfo, gameState, false) {
+		if v != gameState.P3Round.Ban {
+			p2Options = append(p2Options, v)
+		}
+	}
+	if len(solution.P1) != len(p1Options) || len(solution.P2) != len(p2Options) {
+		t.Fatalf("Expected %d by %d strategies but got %+v", len(p1Options), len(p2Options), solution)
+	}
+	payoff := make([][]float64, len(p1Options))
+	rowStrategy := make([]float64, len(p1Options))
+	colStrategy := make([]float64, len(p2Options))
+	for i, p1 := range p1Options {
+		rowStrategy[i] = solution.P1[i].Probability
+		for j, p2 := range p2Options {
+			colStrategy[j] = solution.P2[j].Probability
+			final := deepcopy(gameState)
+			final.P3Round.CounterBan = solution.CounterBan
+			final.P3Round.Matchup = Matchup{P1: p1, P2: p2}
+			payoff[i] = append(payoff[i], computeWinRate(tournamentInfo, final))
+		}
+	}
+	checkEquilibrium(t, payoff, solution.Value, rowStrategy, colStrategy)
+
+	// The line through the blind step has to agree with the equilibrium.
+	value, _ := TurinMinimax(tournamentInfo, gameState, false, -1.0, 2.0)
+	if !(math.Abs(value-solution.Value) < epsilon) {
+		t.Errorf("Expected minimax to find %f but it found %f", solution.Value, value)
+	}
+}
+
+func TestBlindCounterPickHelpsCounterPicker(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+		},
+		P3Round: P3Round{Picks: []Faction{GC, KI, NG}, Ban: SL},
+	}
+
+	// P1 picked first, so hiding P2's counter pick can only cost P1.
+	sequential, _ := TurinMinimax(TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}, gameState, false, -1.0, 2.0)
+	blind, _ := TurinMinimax(TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2Blind}, gameState, false, -1.0, 2.0)
+	if blind > sequential+epsilon {
+		t.Errorf("Expected blind WR %f to be at most sequential WR %f", blind, sequential)
+	}
+}
+
+func TestSolveSimultaneousStepP2Round(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2Blind}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}},
+		},
+		P3Round: P3Round{},
+	}
+
+	solution, ok := SolveSimultaneousStep(tournamentInfo, gameState)
+	if !ok {
+		t.Fatalf("Expected %+v to be waiting on a blind step", gameState)
+	}
+	if solution.Step != CounterPick || solution.CounterBan != EMPTY {
+		t.Errorf("Expected a plain counter pick but got %+v", solution)
+	}
+	// P2 picked first this round, so P2 chooses between their two initial picks.
+	if len(solution.P2) != 2 || len(solution.P1) != 6 {
+		t.Errorf("Expected P1 to have 6 options and P2 2 but got %+v", solution)
+	}
+	checkDistribution(t, []float64{solution.P1[0].Probability, solution.P1[1].Probability, solution.P1[2].Probability,
+		solution.P1[3].Probability, solution.P1[4].Probability, solution.P1[5].Probability})
+	checkDistribution(t, []float64{solution.P2[0].Probability, solution.P2[1].Probability})
+
+	value, _ := TurinMinimax(tournamentInfo, gameState, true, -1.0, 2.0)
+	if !(math.Abs(value-solution.Value) < epsilon) {
+		t.Errorf("Expected minimax to find %f but it found %f", solution.Value, value)
+	}
+}
+
+func TestSolveSimultaneousStepNotBlind(t *testing.T) {
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}},
+		},
+		P3Round: P3Round{},
+	}
+
+	if _, ok := SolveSimultaneousStep(TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}, gameState); ok {
+		t.Errorf("Expected the default ruleset to have no blind steps")
+	}
+}
+
+func TestSearchSimultaneousStepSharesTable(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2Blind}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, TZ}},
+		},
+		P3Round: P3Round{},
+	}
+	expected, _ := SolveSimultaneousStep(tournamentInfo, gameState)
+
+	table := NewTranspositionTable()
+	Search(tournamentInfo, gameState, true, SearchOptions{Table: table})
+	solution, ok, stats := SearchSimultaneousStep(tournamentInfo, gameState, SearchOptions{Table: table})
+	if !ok {
+		t.Fatalf("Expected %+v to be waiting on a blind step", gameState)
+	}
+	if !(math.Abs(expected.Value-solution.Value) < epsilon) {
+		t.Errorf("Expected the table to leave the value at %f but got %f", expected.Value, solution.Value)
+	}
+	// Every pair of blind picks was solved by the first search, so none of them is searched again.
+	if stats.TranspositionHits != len(solution.P1)*len(solution.P2) {
+		t.Errorf("Expected a transposition hit for each of the %d pairs of picks but got %+v", len(solution.P1)*len(solution.P2), stats)
+	}
+}
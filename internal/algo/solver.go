@@ -0,0 +1,53 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"time"
+)
+
+// Solver finds the best line for whoever picks next and reports P1's series win rate along it.
+type Solver interface {
+	Solve(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool) (float64, GameState)
+}
+
+// MinimaxSolver searches every line to the end of the draft. It is exact but gets slow for long series, so positions
+// Book has already solved are taken from it instead.
+type MinimaxSolver struct {
+	Book *OpeningBook
+}
+
+func (s MinimaxSolver) Solve(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool) (float64, GameState) {
+	if s.Book != nil {
+		if entry, ok := s.Book.Lookup(tournamentInfo, gameState); ok {
+			return entry.Value, entry.Line
+		}
+	}
+	return TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+}
+
+// DepthLimitedSolver searches Depth picks ahead and scores whatever is left with Evaluator, PoolEvaluator if unset.
+type DepthLimitedSolver struct {
+	Depth     int
+	Evaluator Evaluator
+}
+
+func (s DepthLimitedSolver) Solve(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool) (float64, GameState) {
+	return TurinMinimaxDepthLimited(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0, s.Depth, evaluatorOrDefault(s.Evaluator))
+}
+
+// TimeLimitedSolver deepens its search until it is exact or Budget is spent, scoring cut off lines with Evaluator.
+type TimeLimitedSolver struct {
+	Budget    time.Duration
+	Evaluator Evaluator
+}
+
+func (s TimeLimitedSolver) Solve(tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool) (float64, GameState) {
+	return TurinMinimaxTimed(tournamentInfo, gameState, isP1PickNext, s.Budget, evaluatorOrDefault(s.Evaluator))
+}
+
+func evaluatorOrDefault(evaluator Evaluator) Evaluator {
+	if evaluator == nil {
+		return PoolEvaluator
+	}
+	return evaluator
+}
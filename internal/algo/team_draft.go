@@ -0,0 +1,210 @@
+package algo
+
+import (
+	"fmt"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+)
+
+/**
+TeamRound is one game of a team draft. The first picking side offers one faction more than it has players, the other
+side picks its whole team in reply and the first side then fields all but one of its offer.
+*/
+type TeamRound struct {
+	Picks   []Faction
+	Matchup TeamMatchup
+	WhoWon  WhoWon
+}
+
+/**
+TeamGameState is a team draft so far. P1 picks first in even games and P2 in odd ones, and no side may field a faction
+it has already played in the series, as the ruleset counts repeats.
+*/
+type TeamGameState struct {
+	Rounds []TeamRound
+}
+
+func teamDraftIsComplete(tournamentInfo TournamentInfo, gameState TeamGameState) bool {
+	return len(gameState.Rounds) == tournamentInfo.RoundCount &&
+		getTeamRoundPhase(tournamentInfo, gameState.Rounds[len(gameState.Rounds)-1], len(gameState.Rounds)%2 == 1) == 2
+}
+
+/**
+Which decision the team draft is waiting on. Must not be called on a complete draft.
+*/
+func TeamNextStep(tournamentInfo TournamentInfo, gameState TeamGameState) DraftStep {
+	switch currentTeamRoundPhase(tournamentInfo, gameState) {
+	case -1, 2:
+		return InitialPicks
+	case 0:
+		return CounterPick
+	default:
+		return FinalPick
+	}
+}
+
+/**
+Whether P1 makes the team draft's next decision. Nobody picks once the draft is complete, so that is false too.
+*/
+func IsP1TeamPickNext(tournamentInfo TournamentInfo, gameState TeamGameState) bool {
+	if teamDraftIsComplete(tournamentInfo, gameState) {
+		return false
+	}
+	if len(gameState.Rounds) == 0 {
+		return true
+	}
+	isP1First := len(gameState.Rounds)%2 == 1
+	switch currentTeamRoundPhase(tournamentInfo, gameState) {
+	case -1:
+		return isP1First
+	case 2:
+		// A new game starts and the other side picks first.
+		return !isP1First
+	case 0:
+		return !isP1First
+	default:
+		return isP1First
+	}
+}
+
+/**
+The phase of the latest game, which is -1 if no game has started yet. Phases are the same as in getP2RoundPhase.
+*/
+func currentTeamRoundPhase(tournamentInfo TournamentInfo, gameState TeamGameState) int {
+	if len(gameState.Rounds) == 0 {
+		return -1
+	}
+	return getTeamRoundPhase(tournamentInfo, gameState.Rounds[len(gameState.Rounds)-1], len(gameState.Rounds)%2 == 1)
+}
+
+func getTeamRoundPhase(tournamentInfo TournamentInfo, currentRound TeamRound, isP1First bool) int {
+	firstSide, otherSide := currentRound.Matchup.P1, currentRound.Matchup.P2
+	if !isP1First {
+		firstSide, otherSide = otherSide, firstSide
+	}
+	teamSize := tournamentInfo.Ruleset.TeamSize
+	if len(firstSide) == teamSize {
+		return 2
+	} else if len(otherSide) == teamSize {
+		return 1
+	} else if len(currentRound.Picks) == teamSize+1 {
+		return 0
+	}
+	return -1
+}
+
+func getTeamSuccessors(tournamentInfo TournamentInfo, previousGameState TeamGameState) []TeamGameState {
+	var successors []TeamGameState
+	teamSize := tournamentInfo.Ruleset.TeamSize
+
+	phase := currentTeamRoundPhase(tournamentInfo, previousGameState)
+	switch phase {
+	case -1, 2:
+		// The game may have been opened already, e.g. to set its map, in which case the picks go into it.
+		opensRound := len(previousGameState.Rounds) == 0 || phase == 2
+		isP1First := len(previousGameState.Rounds)%2 == 0
+		if !opensRound {
+			isP1First = !isP1First
+		}
+		for _, v := range getCombos(getRemainingTeamPicks(tournamentInfo, previousGameState, isP1First), teamSize+1) {
+			successor := deepcopyTeam(previousGameState)
+			if opensRound {
+				successor.Rounds = append(successor.Rounds, TeamRound{Picks: v})
+			} else {
+				successor.Rounds[len(successor.Rounds)-1].Picks = v
+			}
+			successors = append(successors, successor)
+		}
+	case 0:
+		isP1First := len(previousGameState.Rounds)%2 == 1
+		for _, v := range getCombos(getRemainingTeamPicks(tournamentInfo, previousGameState, !isP1First), teamSize) {
+			successor := deepcopyTeam(previousGameState)
+			current := &successor.Rounds[len(successor.Rounds)-1]
+			if isP1First {
+				current.Matchup.P2 = v
+			} else {
+				current.Matchup.P1 = v
+			}
+			successors = append(successors, successor)
+		}
+	case 1:
+		isP1First := len(previousGameState.Rounds)%2 == 1
+		current := previousGameState.Rounds[len(previousGameState.Rounds)-1]
+		for _, v := range getCombos(current.Picks, teamSize) {
+			successor := deepcopyTeam(previousGameState)
+			if isP1First {
+				successor.Rounds[len(successor.Rounds)-1].Matchup.P1 = v
+			} else {
+				successor.Rounds[len(successor.Rounds)-1].Matchup.P2 = v
+			}
+			successors = append(successors, successor)
+		}
+	default:
+		panic(fmt.Sprintf("No successors for a complete team draft"))
+	}
+	return successors
+}
+
+/**
+What a side may still field: its pool less anything it has played in an earlier game.
+*/
+func getRemainingTeamPicks(tournamentInfo TournamentInfo, gameState TeamGameState, isP1 bool) []Faction {
+	ruleset := tournamentInfo.Ruleset
+	var remaining []Faction
+	for _, faction := range tournamentInfo.PlayerPool(isP1) {
+		isRepeat := false
+		for _, round := range gameState.Rounds {
+			played := round.Matchup.P2
+			if isP1 {
+				played = round.Matchup.P1
+			}
+			for _, v := range played {
+				if ruleset.IsRepeat(v, faction) {
+					isRepeat = true
+				}
+			}
+		}
+		if !isRepeat {
+			remaining = append(remaining, faction)
+		}
+	}
+	return remaining
+}
+
+/**
+Every way of choosing size of factions, keeping their order.
+*/
+func getCombos(factions []Faction, size int) [][]Faction {
+	if size == 0 {
+		return [][]Faction{{}}
+	}
+	var combos [][]Faction
+	for i := 0; i+size <= len(factions); i++ {
+		for _, rest := range getCombos(factions[i+1:], size-1) {
+			combos = append(combos, append([]Faction{factions[i]}, rest...))
+		}
+	}
+	return combos
+}
+
+/**
+Copies the rounds but shares their faction slices, which successors replace rather than append to.
+*/
+func deepcopyTeam(state TeamGameState) TeamGameState {
+	rounds := make([]TeamRound, len(state.Rounds))
+	copy(rounds, state.Rounds)
+	return TeamGameState{Rounds: rounds}
+}
+
+/**
+For a complete team draft, compute the odds of P1's side winning the series.
+*/
+func computeTeamWinRate(tournamentInfo TournamentInfo, gameState TeamGameState) float64 {
+	if len(gameState.Rounds) != tournamentInfo.RoundCount {
+		panic(fmt.Sprintf("Expected: %d rounds but got: %d rounds instead.", tournamentInfo.RoundCount, len(gameState.Rounds)))
+	}
+	var gameOdds []float64
+	for _, v := range gameState.Rounds {
+		gameOdds = append(gameOdds, GetTeamMatchupValue(v.Matchup, tournamentInfo))
+	}
+	return seriesWinRate(gameOdds)
+}
@@ -0,0 +1,119 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+)
+
+// TeamEvaluator scores a team draft that may still have picks left to make, as P1's estimated series win rate.
+type TeamEvaluator func(tournamentInfo TournamentInfo, gameState TeamGameState) float64
+
+// teamSearch carries the settings shared by every node of a single team minimax run.
+type teamSearch struct {
+	tournamentInfo TournamentInfo
+	evaluator      TeamEvaluator
+}
+
+/**
+Searches a team draft to the end. Sides strictly take turns in a team draft, so unlike TurinMinimax there is no need
+to watch out for a player moving twice in a row. Team drafts branch far more than Turin ones, so anything longer than
+a game or two from the end is better searched with TeamMinimaxDepthLimited.
+*/
+func TeamMinimax(tournamentInfo TournamentInfo, gameState TeamGameState, isMaximizingPlayer bool, alpha float64, beta float64) (float64, TeamGameState) {
+	s := teamSearch{tournamentInfo: tournamentInfo}
+	return s.minimax(gameState, isMaximizingPlayer, alpha, beta, -1)
+}
+
+/**
+Like TeamMinimax, but only looks depth picks ahead and scores unfinished drafts with evaluator, TeamPoolEvaluator if
+nil.
+*/
+func TeamMinimaxDepthLimited(tournamentInfo TournamentInfo, gameState TeamGameState, isMaximizingPlayer bool, alpha float64, beta float64, depth int, evaluator TeamEvaluator) (float64, TeamGameState) {
+	if evaluator == nil {
+		evaluator = TeamPoolEvaluator
+	}
+	s := teamSearch{tournamentInfo: tournamentInfo, evaluator: evaluator}
+	return s.minimax(gameState, isMaximizingPlayer, alpha, beta, depth)
+}
+
+/**
+A negative depth searches to the end of the draft.
+*/
+func (s *teamSearch) minimax(gameState TeamGameState, isMaximizingPlayer bool, alpha float64, beta float64, depth int) (float64, TeamGameState) {
+	tournamentInfo := s.tournamentInfo
+	if teamDraftIsComplete(tournamentInfo, gameState) {
+		return computeTeamWinRate(tournamentInfo, gameState), gameState
+	}
+	if depth == 0 {
+		return s.evaluator(tournamentInfo, gameState), gameState
+	}
+
+	bestVal := 2.0
+	if isMaximizingPlayer {
+		bestVal = -1.0
+	}
+	var bestGameState TeamGameState
+	for _, v := range getTeamSuccessors(tournamentInfo, gameState) {
+		value, candidateGameState := s.minimax(v, IsP1TeamPickNext(tournamentInfo, v), alpha, beta, depth-1)
+		if isMaximizingPlayer {
+			if value > bestVal {
+				bestVal, bestGameState = value, candidateGameState
+			}
+			alpha = math.Max(alpha, bestVal)
+		} else {
+			if value < bestVal {
+				bestVal, bestGameState = value, candidateGameState
+			}
+			beta = math.Min(beta, bestVal)
+		}
+		if beta <= alpha {
+			break
+		}
+	}
+	return bestVal, bestGameState
+}
+
+/**
+The team draft version of PoolEvaluator. Games with both teams set use their real odds. Otherwise each side is scored
+as if it fielded every faction it still might in that game at once - its final picks if made, its offer if it picks
+first and has made one, or else its whole remaining pool - which for AverageTeamOdds is the average over every team it
+could field.
+*/
+func TeamPoolEvaluator(tournamentInfo TournamentInfo, gameState TeamGameState) float64 {
+	if teamDraftIsComplete(tournamentInfo, gameState) {
+		return computeTeamWinRate(tournamentInfo, gameState)
+	}
+
+	p1Pool := getRemainingTeamPicks(tournamentInfo, gameState, true)
+	p2Pool := getRemainingTeamPicks(tournamentInfo, gameState, false)
+
+	var gameOdds []float64
+	for i, round := range gameState.Rounds {
+		isP1First := i%2 == 0
+		p1Options, p2Options := round.Matchup.P1, round.Matchup.P2
+		if len(p1Options) == 0 {
+			p1Options = p1Pool
+			if isP1First && len(round.Picks) > 0 {
+				p1Options = round.Picks
+			}
+		}
+		if len(p2Options) == 0 {
+			p2Options = p2Pool
+			if !isP1First && len(round.Picks) > 0 {
+				p2Options = round.Picks
+			}
+		}
+		gameOdds = append(gameOdds, teamOptionsValue(tournamentInfo, p1Options, p2Options, round.Matchup.Map))
+	}
+	for i := len(gameState.Rounds); i < tournamentInfo.RoundCount; i++ {
+		gameOdds = append(gameOdds, teamOptionsValue(tournamentInfo, p1Pool, p2Pool, ""))
+	}
+	return seriesWinRate(gameOdds)
+}
+
+func teamOptionsValue(tournamentInfo TournamentInfo, p1Options []Faction, p2Options []Faction, gameMap GameMap) float64 {
+	if len(p1Options) == 0 || len(p2Options) == 0 {
+		return .5
+	}
+	return GetTeamMatchupValue(TeamMatchup{P1: p1Options, P2: p2Options, Map: gameMap}, tournamentInfo)
+}
@@ -0,0 +1,110 @@
+package algo
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var teamTournamentInfo = TournamentInfo{RoundCount: 1, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q22v2}
+
+func TestGetTeamMatchupValue(t *testing.T) {
+	odds := map[Matchup]float64{
+		{P1: GC, P2: KH}: .5,
+		{P1: GC, P2: KI}: .5,
+		{P1: SL, P2: KH}: .5,
+		{P1: SL, P2: KI}: .9,
+	}
+	matchup := TeamMatchup{P1: []Faction{GC, SL}, P2: []Faction{KH, KI}}
+	cases := []struct {
+		model    TeamOddsModel
+		expected float64
+	}{
+		{AverageTeamOdds, .6},
+		{"", .6},
+		// The mean log odds is ln(9)/4, i.e. odds of sqrt(3) to 1.
+		{LogitTeamOdds, math.Sqrt(3) / (1 + math.Sqrt(3))},
+	}
+	for _, v := range cases {
+		tournamentInfo := TournamentInfo{MatchupOdds: odds, Ruleset: Ruleset{TeamSize: 2, TeamOdds: v.model}}
+		actual := GetTeamMatchupValue(matchup, tournamentInfo)
+		if !(math.Abs(actual-v.expected) < epsilon) {
+			t.Errorf("Expected %q to combine to %f but got %f", v.model, v.expected, actual)
+		}
+		mirrored := GetTeamMatchupValue(TeamMatchup{P1: matchup.P2, P2: matchup.P1}, tournamentInfo)
+		if !(math.Abs(mirrored-(1-v.expected)) < epsilon) {
+			t.Errorf("Expected %q to give the other side %f but got %f", v.model, 1-v.expected, mirrored)
+		}
+	}
+}
+
+func TestTeamStepOrder(t *testing.T) {
+	tournamentInfo := teamTournamentInfo
+	tournamentInfo.RoundCount = 2
+
+	type move struct {
+		step DraftStep
+		isP1 bool
+	}
+	expected := []move{
+		{InitialPicks, true}, {CounterPick, false}, {FinalPick, true},
+		{InitialPicks, false}, {CounterPick, true}, {FinalPick, false},
+	}
+	gameState := TeamGameState{}
+	var actual []move
+	for !teamDraftIsComplete(tournamentInfo, gameState) {
+		actual = append(actual, move{TeamNextStep(tournamentInfo, gameState), IsP1TeamPickNext(tournamentInfo, gameState)})
+		gameState = getTeamSuccessors(tournamentInfo, gameState)[0]
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected the team draft to go %v but got %v", expected, actual)
+	}
+
+	// Both sides field GC and KH in the first game, so can't play them again in the second.
+	first, second := gameState.Rounds[0].Matchup, gameState.Rounds[1].Matchup
+	if !reflect.DeepEqual(first.P1, []Faction{GC, KH}) || !reflect.DeepEqual(first.P2, []Faction{GC, KH}) ||
+		!reflect.DeepEqual(second.P1, []Faction{KI, NG}) || !reflect.DeepEqual(second.P2, []Faction{KI, NG}) {
+		t.Errorf("Expected [GC KH] v. [GC KH] then [KI NG] v. [KI NG] but got %+v", gameState.Rounds)
+	}
+}
+
+func TestTeamMinimaxSingleGame(t *testing.T) {
+	// Search a single game by hand: P1 offers three, P2 answers with a team, P1 fields the best two of its offer.
+	pool := Turin2022Q22v2.Pool()
+	expected := -1.0
+	for _, offer := range getCombos(pool, 3) {
+		worst := 2.0
+		for _, p2Team := range getCombos(pool, 2) {
+			best := -1.0
+			for _, p1Team := range getCombos(offer, 2) {
+				best = math.Max(best, GetTeamMatchupValue(TeamMatchup{P1: p1Team, P2: p2Team}, teamTournamentInfo))
+			}
+			worst = math.Min(worst, best)
+		}
+		expected = math.Max(expected, worst)
+	}
+
+	actual, line := TeamMinimax(teamTournamentInfo, TeamGameState{}, true, -1.0, 2.0)
+	if !(math.Abs(actual-expected) < epsilon) {
+		t.Errorf("Expected a single team game to be worth %f but got %f", expected, actual)
+	}
+	if !teamDraftIsComplete(teamTournamentInfo, line) {
+		t.Errorf("Expected the line to finish the draft but got %+v", line)
+	}
+}
+
+func TestTeamMinimaxDepthLimited(t *testing.T) {
+	exact, _ := TeamMinimax(teamTournamentInfo, TeamGameState{}, true, -1.0, 2.0)
+	deep, _ := TeamMinimaxDepthLimited(teamTournamentInfo, TeamGameState{}, true, -1.0, 2.0, 3, nil)
+	if !(math.Abs(exact-deep) < epsilon) {
+		t.Errorf("Expected a search deep enough to finish the draft to be exact, %f, but got %f", exact, deep)
+	}
+
+	tournamentInfo := teamTournamentInfo
+	tournamentInfo.RoundCount = 3
+	value, line := TeamMinimaxDepthLimited(tournamentInfo, TeamGameState{}, true, -1.0, 2.0, 2, nil)
+	if value < 0 || value > 1 || len(line.Rounds) != 1 || len(line.Rounds[0].Matchup.P2) != 2 {
+		t.Errorf("Expected two picks into a Bo3 to set P2's first team but got %f and %+v", value, line)
+	}
+}
@@ -22,6 +22,12 @@ type P3Round struct {
 type GameState struct {
 	P2Rounds []P2Round
 	P3Round  P3Round
+	// MapBans are the maps banned before the first game, alternating between P1 and P2 starting with P1.
+	MapBans []GameMap
+	// P1Bans and P2Bans are the factions each player banned for the whole series before anything else, taking turns
+	// starting with P1. A player's bans apply to their opponent.
+	P1Bans []Faction
+	P2Bans []Faction
 }
 
 type resultAndOdds struct {
@@ -30,13 +36,79 @@ type resultAndOdds struct {
 }
 
 func getSuccessors(tournamentInfo TournamentInfo, previousGameState GameState) []GameState {
-	isFinalRound := len(previousGameState.P2Rounds) == tournamentInfo.RoundCount-1 &&
-		previousGameState.P2Rounds[len(previousGameState.P2Rounds)-1].Matchup.P1 != EMPTY &&
-		previousGameState.P2Rounds[len(previousGameState.P2Rounds)-1].Matchup.P2 != EMPTY
-	if isFinalRound {
-		return getSuccessorsP3(previousGameState)
+	if globalBansRemain(tournamentInfo, previousGameState) {
+		return getSuccessorsGlobalBan(tournamentInfo, previousGameState)
+	}
+	if resultIsNext(tournamentInfo, previousGameState) {
+		return getSuccessorsResult(previousGameState)
+	}
+	if mapBansRemain(tournamentInfo, previousGameState) || mapPickIsNext(tournamentInfo, previousGameState) {
+		return getSuccessorsMap(tournamentInfo, previousGameState)
+	}
+	if isFinalRound(tournamentInfo, previousGameState) {
+		return getSuccessorsP3(tournamentInfo, previousGameState)
 	} else {
-		return getSuccessorsP2(previousGameState)
+		return getSuccessorsP2(tournamentInfo, previousGameState)
+	}
+}
+
+func isFinalRound(tournamentInfo TournamentInfo, gameState GameState) bool {
+	if len(gameState.P2Rounds) != tournamentInfo.RoundCount-1 {
+		return false
+	}
+	// A Bo1 is nothing but its final game.
+	if len(gameState.P2Rounds) == 0 {
+		return true
+	}
+	lastRound := gameState.P2Rounds[len(gameState.P2Rounds)-1]
+	return lastRound.Matchup.P1 != EMPTY && lastRound.Matchup.P2 != EMPTY
+}
+
+/**
+Which decision the draft is waiting on. Must not be called on a complete draft.
+*/
+func NextStep(tournamentInfo TournamentInfo, gameState GameState) DraftStep {
+	if globalBansRemain(tournamentInfo, gameState) {
+		return GlobalBan
+	}
+	if resultIsNext(tournamentInfo, gameState) {
+		return GameResult
+	}
+	if mapBansRemain(tournamentInfo, gameState) {
+		return MapBan
+	}
+	if mapPickIsNext(tournamentInfo, gameState) {
+		return MapPick
+	}
+	if isFinalRound(tournamentInfo, gameState) {
+		isP1Pick := isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds))
+		phase := getP3RoundPhase(gameState.P3Round, isP1Pick)
+		switch phase {
+		case -1:
+			return LastInitialPicks
+		case 0:
+			return LastCounterPick
+		case 1:
+			return LastFinalPick
+		default:
+			panic(fmt.Sprintf("Illegal phase: %d", phase))
+		}
+	}
+
+	if len(gameState.P2Rounds) == 0 {
+		return InitialPicks
+	}
+	isP1Pick := isP1FirstInGame(tournamentInfo, gameState, len(gameState.P2Rounds)-1)
+	phase := getP2RoundPhase(gameState.P2Rounds[len(gameState.P2Rounds)-1], isP1Pick)
+	switch phase {
+	case -1, 2:
+		return InitialPicks
+	case 0:
+		return CounterPick
+	case 1:
+		return FinalPick
+	default:
+		panic(fmt.Sprintf("Illegal phase: %d", phase))
 	}
 }
 
@@ -49,13 +121,14 @@ There are 3 cases:
 We also must figure out who is picking first, p1, or p2 from gamestate.
 We also need to determine what factions remain for each player if we are in 1 or 3.
 */
-func getSuccessorsP2(previousGameState GameState) []GameState {
+func getSuccessorsP2(tournamentInfo TournamentInfo, previousGameState GameState) []GameState {
 	var successors []GameState
 
-	isP1Pick := len(previousGameState.P2Rounds)%2 == 1
+	var isP1Pick bool
 	var lastRoundsPhase int
 	if len(previousGameState.P2Rounds) > 0 {
 		currentRound := previousGameState.P2Rounds[len(previousGameState.P2Rounds)-1]
+		isP1Pick = isP1FirstInGame(tournamentInfo, previousGameState, len(previousGameState.P2Rounds)-1)
 		lastRoundsPhase = getP2RoundPhase(currentRound, isP1Pick)
 	} else {
 		lastRoundsPhase = -1
@@ -63,16 +136,25 @@ func getSuccessorsP2(previousGameState GameState) []GameState {
 
 	switch lastRoundsPhase {
 	case -1, 2:
-		pickCombos := getTwoCombos(previousGameState, isP1Pick)
+		// A map pick may already have opened the round, in which case the picks go into it. Either way they come from
+		// the pool of whoever picks first in that round, less what they have already played, as they must play one.
+		opensRound := len(previousGameState.P2Rounds) == 0 || lastRoundsPhase == 2
+		isP1First := isP1Pick
+		if opensRound {
+			isP1First = isP1FirstInGame(tournamentInfo, previousGameState, len(previousGameState.P2Rounds))
+		}
+		pickCombos := getTwoCombos(tournamentInfo, previousGameState, isP1First)
 		for _, v := range pickCombos {
 			newGameState := deepcopy(previousGameState)
-			newGameState.P2Rounds = append(newGameState.P2Rounds, P2Round{})
+			if opensRound {
+				newGameState.P2Rounds = append(newGameState.P2Rounds, P2Round{})
+			}
 			newGameState.P2Rounds[len(newGameState.P2Rounds)-1].Picks = v
 			successors = append(successors, newGameState)
 		}
 		return successors
 	case 0:
-		remainingPicks := getRemainingPicks(previousGameState, !isP1Pick)
+		remainingPicks := getRemainingPicks(tournamentInfo, previousGameState, !isP1Pick)
 		for _, v := range remainingPicks {
 			newGameState := deepcopy(previousGameState)
 			if isP1Pick {
@@ -113,8 +195,8 @@ There are 3 cases:
 We also must figure out who is picking first, p1 or p2 from gamestate.
 We also need to determine what factions remain for each player in 1 or 3.
 */
-func getSuccessorsP3(previousGameState GameState) []GameState {
-	isP1Pick := previousGameState.P2Rounds[len(previousGameState.P2Rounds)-1].WhoWon != P2
+func getSuccessorsP3(tournamentInfo TournamentInfo, previousGameState GameState) []GameState {
+	isP1Pick := isP1FirstInGame(tournamentInfo, previousGameState, len(previousGameState.P2Rounds))
 
 	roundPhase := getP3RoundPhase(previousGameState.P3Round, isP1Pick)
 
@@ -122,7 +204,7 @@ func getSuccessorsP3(previousGameState GameState) []GameState {
 
 	switch roundPhase {
 	case -1:
-		pickCombos := getThreeCombos(previousGameState, isP1Pick)
+		pickCombos := getThreeCombos(tournamentInfo, previousGameState, isP1Pick)
 		for _, initialPicks := range pickCombos {
 			newGameState := deepcopy(previousGameState)
 			newGameState.P3Round = P3Round{
@@ -130,12 +212,13 @@ func getSuccessorsP3(previousGameState GameState) []GameState {
 				Ban:        EMPTY,
 				CounterBan: EMPTY,
 				Matchup: Matchup{
-					P1: EMPTY,
-					P2: EMPTY,
+					P1:  EMPTY,
+					P2:  EMPTY,
+					Map: previousGameState.P3Round.Matchup.Map,
 				}}
 			newGameState.P3Round.Picks = initialPicks
 
-			remainingBans := getRemainingPicks(previousGameState, !isP1Pick)
+			remainingBans := getRemainingPicks(tournamentInfo, previousGameState, !isP1Pick)
 			for _, ban := range remainingBans {
 				newGameStateWithBan := deepcopy(newGameState)
 				newGameStateWithBan.P3Round.Ban = ban
@@ -146,7 +229,7 @@ func getSuccessorsP3(previousGameState GameState) []GameState {
 	case 0:
 		counterBans := previousGameState.P3Round.Picks
 		for _, counterBan := range counterBans {
-			remainingPicks := getRemainingPicks(previousGameState, !isP1Pick)
+			remainingPicks := getRemainingPicks(tournamentInfo, previousGameState, !isP1Pick)
 
 			for _, pick := range remainingPicks {
 				if pick == previousGameState.P3Round.Ban {
@@ -183,31 +266,44 @@ func getSuccessorsP3(previousGameState GameState) []GameState {
 	}
 }
 
-func getRemainingPicks(previousGameState GameState, isP1 bool) []Faction {
-	remainingFactions := map[Faction]bool{}
-	for k, v := range Factions {
-		remainingFactions[k] = v
-	}
-	for _, v := range previousGameState.P2Rounds {
-		if isP1 {
-			delete(remainingFactions, v.Matchup.P1)
-		} else {
-			delete(remainingFactions, v.Matchup.P2)
-		}
+/**
+What a player can still pick from their pool, sorted, given what they have played so far, what they have won with if
+the ruleset locks winners out, and what their opponent banned.
+*/
+func getRemainingPicks(tournamentInfo TournamentInfo, previousGameState GameState, isP1 bool) []Faction {
+	ruleset := tournamentInfo.Ruleset
+	opponentBans := previousGameState.P1Bans
+	if isP1 {
+		opponentBans = previousGameState.P2Bans
 	}
 	var remainingFactionsList []Faction
-
-	for k, _ := range remainingFactions {
-		remainingFactionsList = append(remainingFactionsList, k)
+	for _, faction := range tournamentInfo.PlayerPool(isP1) {
+		if isGloballyBanned(opponentBans, faction) {
+			continue
+		}
+		if ruleset.WinnerLockedOut && wonWith(previousGameState, isP1, faction) {
+			continue
+		}
+		isRepeat := false
+		for _, v := range previousGameState.P2Rounds {
+			played := v.Matchup.P2
+			if isP1 {
+				played = v.Matchup.P1
+			}
+			if played != EMPTY && ruleset.IsRepeat(played, faction) {
+				isRepeat = true
+				break
+			}
+		}
+		if !isRepeat {
+			remainingFactionsList = append(remainingFactionsList, faction)
+		}
 	}
-	sort.Slice(remainingFactionsList, func(i, j int) bool {
-		return remainingFactionsList[i] < remainingFactionsList[j]
-	})
 	return remainingFactionsList
 }
 
-func getTwoCombos(state GameState, isP1Pick bool) [][]Faction {
-	remainingFactions := getRemainingPicks(state, isP1Pick)
+func getTwoCombos(tournamentInfo TournamentInfo, state GameState, isP1Pick bool) [][]Faction {
+	remainingFactions := getRemainingPicks(tournamentInfo, state, isP1Pick)
 	var combos [][]Faction
 	for i, v := range remainingFactions {
 		for j := i + 1; j < len(remainingFactions); j++ {
@@ -217,8 +313,8 @@ func getTwoCombos(state GameState, isP1Pick bool) [][]Faction {
 	return combos
 }
 
-func getThreeCombos(state GameState, isP1Pick bool) [][]Faction {
-	remainingFactions := getRemainingPicks(state, isP1Pick)
+func getThreeCombos(tournamentInfo TournamentInfo, state GameState, isP1Pick bool) [][]Faction {
+	remainingFactions := getRemainingPicks(tournamentInfo, state, isP1Pick)
 	var combos [][]Faction
 	for i, v := range remainingFactions {
 		for j := i + 1; j < len(remainingFactions); j++ {
@@ -235,8 +331,9 @@ func deepcopy(state GameState) GameState {
 	copy(p2Rounds, state.P2Rounds)
 
 	var p3RoundMatchup = Matchup{
-		P1: state.P3Round.Matchup.P1,
-		P2: state.P3Round.Matchup.P2}
+		P1:  state.P3Round.Matchup.P1,
+		P2:  state.P3Round.Matchup.P2,
+		Map: state.P3Round.Matchup.Map}
 
 	var p3RoundPicks []Faction
 	copy(state.P3Round.Picks, p3RoundPicks)
@@ -247,16 +344,77 @@ func deepcopy(state GameState) GameState {
 		state.P3Round.CounterBan,
 		p3RoundMatchup}
 
+	var mapBans []GameMap
+	if state.MapBans != nil {
+		mapBans = make([]GameMap, len(state.MapBans))
+		copy(mapBans, state.MapBans)
+	}
+
 	return GameState{
 		P2Rounds: p2Rounds,
 		P3Round:  p3RoundCopy,
+		MapBans:  mapBans,
+		P1Bans:   copyFactions(state.P1Bans),
+		P2Bans:   copyFactions(state.P2Bans),
 	}
 }
 
 /**
-For one specific gamestate consisting of a full set of games, compute the odds of player one winning.
+The game state with empty lists made nil and initial picks sorted, so that drafts that only differ in how they were
+written down, such as an empty list of bans against none at all or KH GC against GC KH, encode the same. Bans keep their
+order.
+*/
+func CanonicalGameState(gameState GameState) GameState {
+	var p2Rounds []P2Round
+	for _, v := range gameState.P2Rounds {
+		v.Picks = sortedPicks(v.Picks)
+		p2Rounds = append(p2Rounds, v)
+	}
+	gameState.P2Rounds = p2Rounds
+	gameState.P3Round.Picks = sortedPicks(gameState.P3Round.Picks)
+	gameState.P1Bans = canonicalPicks(gameState.P1Bans)
+	gameState.P2Bans = canonicalPicks(gameState.P2Bans)
+	if len(gameState.MapBans) == 0 {
+		gameState.MapBans = nil
+	}
+	return gameState
+}
+
+func canonicalPicks(picks []Faction) []Faction {
+	if len(picks) == 0 {
+		return nil
+	}
+	return picks
+}
+
+func sortedPicks(picks []Faction) []Faction {
+	sorted := canonicalPicks(copyFactions(picks))
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+func copyFactions(factions []Faction) []Faction {
+	if factions == nil {
+		return nil
+	}
+	copied := make([]Faction, len(factions))
+	copy(copied, factions)
+	return copied
+}
+
+/**
+For one specific gamestate consisting of a full set of games, compute the odds of player one winning. Games with a
+recorded result are certain, and a series those results have already decided needs no more games at all.
 */
 func computeWinRate(tournamentInfo TournamentInfo, gameState GameState) float64 {
+	switch seriesWinner(tournamentInfo, gameState) {
+	case P1:
+		return 1.0
+	case P2:
+		return 0.0
+	}
 	// Validate input sanity
 	if gameState.P3Round.Matchup.P1 == EMPTY {
 		panic(fmt.Sprintf("Expected p3Round to be set but it was not"))
@@ -266,17 +424,68 @@ func computeWinRate(tournamentInfo TournamentInfo, gameState GameState) float64
 		panic(fmt.Sprintf("Expected: %d rounds but got: %d rounds instead.", tournamentInfo.RoundCount, eventLength))
 	}
 
-	var matchups []Matchup
+	var gameOdds []float64
 	for _, v := range gameState.P2Rounds {
-		matchups = append(matchups, v.Matchup)
+		gameOdds = append(gameOdds, roundOdds(tournamentInfo, v))
 	}
-	matchups = append(matchups, gameState.P3Round.Matchup)
+	gameOdds = append(gameOdds, GetMatchupValue(gameState.P3Round.Matchup, tournamentInfo))
+
+	return seriesWinRate(gameOdds)
+}
 
+/**
+P1's odds of winning a game before the last: certain either way once its result is recorded.
+*/
+func roundOdds(tournamentInfo TournamentInfo, round P2Round) float64 {
+	switch round.WhoWon {
+	case P1:
+		return 1.0
+	case P2:
+		return 0.0
+	}
+	return GetMatchupValue(round.Matchup, tournamentInfo)
+}
+
+/**
+The player the results recorded in gameState have already won the series for, or NoOneYet.
+*/
+func SeriesWinner(tournamentInfo TournamentInfo, gameState GameState) WhoWon {
+	return seriesWinner(tournamentInfo, gameState)
+}
+
+/**
+Series are first to a majority of their games: once a player has won that many the series is over and the rest of its
+games aren't played. Returns the player who has, going by the results recorded so far, or NoOneYet.
+*/
+func seriesWinner(tournamentInfo TournamentInfo, gameState GameState) WhoWon {
+	needed := tournamentInfo.RoundCount/2 + 1
+	p1Wins, p2Wins := 0, 0
+	for _, v := range gameState.P2Rounds {
+		switch v.WhoWon {
+		case P1:
+			p1Wins++
+		case P2:
+			p2Wins++
+		}
+	}
+	if p1Wins >= needed {
+		return P1
+	} else if p2Wins >= needed {
+		return P2
+	}
+	return NoOneYet
+}
+
+/**
+Given P1's odds of winning each game of a series, compute the odds of P1 winning a majority of them. Games are
+independent, so this is also the odds of P1 getting to a majority first when the series stops there.
+*/
+func seriesWinRate(gameOdds []float64) float64 {
 	// Expand the result tree
 	var results [][]resultAndOdds
 	var stack [][]resultAndOdds
 
-	r1Odds := GetMatchupValue(matchups[0], tournamentInfo)
+	r1Odds := gameOdds[0]
 	stack = append(stack, []resultAndOdds{{true, r1Odds}})
 	stack = append(stack, []resultAndOdds{{false, 1.0 - r1Odds}})
 
@@ -284,10 +493,10 @@ func computeWinRate(tournamentInfo TournamentInfo, gameState GameState) float64
 		current := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
 
-		if len(current) == len(matchups) {
+		if len(current) == len(gameOdds) {
 			results = append(results, current)
 		} else {
-			rNextOdds := GetMatchupValue(matchups[len(current)], tournamentInfo)
+			rNextOdds := gameOdds[len(current)]
 			nextWin := make([]resultAndOdds, len(current))
 			nextLoss := make([]resultAndOdds, len(current))
 			copy(nextWin, current)
@@ -311,8 +520,8 @@ func computeWinRate(tournamentInfo TournamentInfo, gameState GameState) float64
 			}
 			resultProbability *= v.odds
 		}
-		// > half the number of matchups is a win, so append the odds of this variant occurring
-		if p1WinTotal > len(matchups)/2 {
+		// > half the number of games is a win, so append the odds of this variant occurring
+		if p1WinTotal > len(gameOdds)/2 {
 			p1WinOdds = append(p1WinOdds, resultProbability)
 		}
 	}
@@ -326,22 +535,30 @@ func computeWinRate(tournamentInfo TournamentInfo, gameState GameState) float64
 }
 
 /**
-From game state and tournament info, interprets whether P1 is picking next or not.
+From game state and tournament info, interprets whether P1 is picking next or not. Nobody picks once the draft is
+complete, so that is false too.
 */
 func IsP1PickNext(tournamentInfo TournamentInfo, gameState GameState) bool {
-	if len(gameState.P2Rounds) == 0 {
-		return true
+	if draftIsComplete(tournamentInfo, gameState) {
+		return false
+	}
+	if globalBansRemain(tournamentInfo, gameState) {
+		return isP1GlobalBanNext(gameState)
+	}
+	if resultIsNext(tournamentInfo, gameState) {
+		// Neither player decides a game's result.
+		return false
+	}
+	if mapBansRemain(tournamentInfo, gameState) {
+		return len(gameState.MapBans)%2 == 0
+	}
+	if mapPickIsNext(tournamentInfo, gameState) {
+		return isP1MapPicker(tournamentInfo, gameState)
 	}
 	p2Rounds := gameState.P2Rounds
 
-	lastRound := p2Rounds[len(p2Rounds)-1]
-
-	p2RoundsFull := len(p2Rounds) == (tournamentInfo.RoundCount - 1)
-	lastP2RoundEnded := (lastRound.Matchup.P1 != EMPTY) && (lastRound.Matchup.P2 != EMPTY)
-	startedRound3 := p2RoundsFull && lastP2RoundEnded
-
-	if startedRound3 {
-		isP2First := lastRound.WhoWon == P2
+	if isFinalRound(tournamentInfo, gameState) {
+		isP2First := !isP1FirstInGame(tournamentInfo, gameState, len(p2Rounds))
 		phase := getP3RoundPhase(gameState.P3Round, !isP2First)
 		if isP2First {
 			switch phase {
@@ -366,10 +583,15 @@ func IsP1PickNext(tournamentInfo TournamentInfo, gameState GameState) bool {
 				panic(fmt.Sprintf("Illegal phase: %d", phase))
 			}
 		}
+	} else if len(p2Rounds) == 0 {
+		return true
 	} else {
-		// P1 has odd rounds p2 has even rounds - determine if the current round was p1 or 2
-		isP1FirstRound := len(p2Rounds) == 0 || len(p2Rounds)%2 == 1
+		// Determine if the current round was p1 or p2's to pick first, and once it is over, whose the next one is
+		isP1FirstRound := isP1FirstInGame(tournamentInfo, gameState, len(p2Rounds)-1)
 		phase := getP2RoundPhase(p2Rounds[len(p2Rounds)-1], isP1FirstRound)
+		if phase == 2 {
+			return isP1FirstInGame(tournamentInfo, gameState, len(p2Rounds))
+		}
 		if isP1FirstRound {
 			switch phase {
 			case 1:
@@ -378,8 +600,6 @@ func IsP1PickNext(tournamentInfo TournamentInfo, gameState GameState) bool {
 				return false
 			case -1:
 				return true
-			case 2:
-				return false
 			default:
 				panic(fmt.Sprintf("Illegal phase: %d", phase))
 			}
@@ -391,8 +611,6 @@ func IsP1PickNext(tournamentInfo TournamentInfo, gameState GameState) bool {
 				return true
 			case -1:
 				return false
-			case 2:
-				return true
 			default:
 				panic(fmt.Sprintf("Illegal phase: %d", phase))
 			}
@@ -451,3 +669,72 @@ func getP2RoundPhase(currentRound P2Round, isP1Pick bool) int {
 	}
 	return roundPhase
 }
+
+/**
+Whether descendant can be reached from ancestor by making more picks, i.e. every decision recorded in ancestor was
+made the same way in descendant. Initial picks are compared without regard to order.
+*/
+func isAncestor(ancestor GameState, descendant GameState) bool {
+	if len(ancestor.P2Rounds) > len(descendant.P2Rounds) || len(ancestor.MapBans) > len(descendant.MapBans) {
+		return false
+	}
+	for i, v := range ancestor.MapBans {
+		if v != descendant.MapBans[i] {
+			return false
+		}
+	}
+	if !isPrefix(ancestor.P1Bans, descendant.P1Bans) || !isPrefix(ancestor.P2Bans, descendant.P2Bans) {
+		return false
+	}
+	for i, v := range ancestor.P2Rounds {
+		other := descendant.P2Rounds[i]
+		if (len(v.Picks) > 0 && !samePicks(v.Picks, other.Picks)) ||
+			!sameOrUnset(v.Matchup.P1, other.Matchup.P1) ||
+			!sameOrUnset(v.Matchup.P2, other.Matchup.P2) ||
+			(v.Matchup.Map != "" && v.Matchup.Map != other.Matchup.Map) ||
+			(v.WhoWon != NoOneYet && v.WhoWon != other.WhoWon) {
+			return false
+		}
+	}
+	ancestorP3 := ancestor.P3Round
+	descendantP3 := descendant.P3Round
+	return (len(ancestorP3.Picks) == 0 || samePicks(ancestorP3.Picks, descendantP3.Picks)) &&
+		sameOrUnset(ancestorP3.Ban, descendantP3.Ban) &&
+		sameOrUnset(ancestorP3.CounterBan, descendantP3.CounterBan) &&
+		sameOrUnset(ancestorP3.Matchup.P1, descendantP3.Matchup.P1) &&
+		sameOrUnset(ancestorP3.Matchup.P2, descendantP3.Matchup.P2) &&
+		(ancestorP3.Matchup.Map == "" || ancestorP3.Matchup.Map == descendantP3.Matchup.Map)
+}
+
+func isPrefix(prefix []Faction, factions []Faction) bool {
+	if len(prefix) > len(factions) {
+		return false
+	}
+	for i, v := range prefix {
+		if v != factions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameOrUnset(ancestor Faction, descendant Faction) bool {
+	return ancestor == EMPTY || ancestor == descendant
+}
+
+func samePicks(a []Faction, b []Faction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[Faction]int{}
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		counts[v]--
+		if counts[v] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	. "github.com/tmwilder/wh3-draftbot/internal/common"
 	"math"
+	"reflect"
 	"testing"
 )
 
 const epsilon = .00000001
 
+// Races only, for tests that don't care about the rest of the tournament.
+var defaultTournamentInfo = TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+
 func TestGetSuccessorsP3(t *testing.T) {
 	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
 	gameState := GameState{
@@ -85,8 +89,8 @@ func TestPick3Combo5thPick(t *testing.T) {
 		P3Round: P3Round{},
 	}
 
-	p1Combos := getThreeCombos(gameState, true)
-	p2Combos := getThreeCombos(gameState, false)
+	p1Combos := getThreeCombos(defaultTournamentInfo, gameState, true)
+	p2Combos := getThreeCombos(defaultTournamentInfo, gameState, false)
 
 	var expected = 1
 
@@ -107,8 +111,8 @@ func TestPick3Combo3rdPick(t *testing.T) {
 		P3Round: P3Round{},
 	}
 
-	p1Combos := getThreeCombos(gameState, true)
-	p2Combos := getThreeCombos(gameState, false)
+	p1Combos := getThreeCombos(defaultTournamentInfo, gameState, true)
+	p2Combos := getThreeCombos(defaultTournamentInfo, gameState, false)
 
 	var expected = 10
 
@@ -127,8 +131,8 @@ func TestPick2Combo1stPick(t *testing.T) {
 		P3Round:  P3Round{},
 	}
 
-	p1Combos := getTwoCombos(gameState, true)
-	p2Combos := getTwoCombos(gameState, false)
+	p1Combos := getTwoCombos(defaultTournamentInfo, gameState, true)
+	p2Combos := getTwoCombos(defaultTournamentInfo, gameState, false)
 
 	var expected = 21
 
@@ -149,8 +153,8 @@ func TestPick2Combo3rdPick(t *testing.T) {
 		P3Round: P3Round{},
 	}
 
-	p1Combos := getTwoCombos(gameState, true)
-	p2Combos := getTwoCombos(gameState, false)
+	p1Combos := getTwoCombos(defaultTournamentInfo, gameState, true)
+	p2Combos := getTwoCombos(defaultTournamentInfo, gameState, false)
 
 	var expected = 10
 
@@ -172,8 +176,8 @@ func TestPick2Combo4thPick(t *testing.T) {
 		P3Round: P3Round{},
 	}
 
-	p1Combos := getTwoCombos(gameState, true)
-	p2Combos := getTwoCombos(gameState, false)
+	p1Combos := getTwoCombos(defaultTournamentInfo, gameState, true)
+	p2Combos := getTwoCombos(defaultTournamentInfo, gameState, false)
 
 	var expected = 6
 
@@ -512,3 +516,139 @@ func TestIsP1PickNext(t *testing.T) {
 		t.Errorf("Expected %+v to result in is it p1 turn next?: %t", gameState, expected)
 	}
 }
+
+func TestPlayerPools(t *testing.T) {
+	p1Pool := []Faction{GC, KH, KI, NG, OK}
+	p2Pool := []Faction{KI, NG, OK, SL, TZ}
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, P1Pool: p1Pool, P2Pool: p2Pool}
+
+	inPool := func(pool []Faction, factions ...Faction) bool {
+		for _, f := range factions {
+			found := false
+			for _, v := range pool {
+				found = found || v == f
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	_, line := TurinMinimax(tournamentInfo, GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}, true, -1.0, 2.0)
+	for i, v := range line.P2Rounds {
+		// P1 picks first in even games, P2 in odd ones, and the initial picks come from the first picker's pool.
+		firstPickerPool := p1Pool
+		if i%2 == 1 {
+			firstPickerPool = p2Pool
+		}
+		if !inPool(firstPickerPool, v.Picks...) || !inPool(p1Pool, v.Matchup.P1) || !inPool(p2Pool, v.Matchup.P2) {
+			t.Errorf("Expected game %d to stick to each player's pool but got %+v", i+1, v)
+		}
+	}
+	if !inPool(p1Pool, line.P3Round.Matchup.P1) || !inPool(p2Pool, line.P3Round.Matchup.P2) {
+		t.Errorf("Expected the final game to stick to each player's pool but got %+v", line.P3Round)
+	}
+}
+
+func TestBo1(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 1, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}
+
+	// The only game is drafted like the final game of a longer series, with P1 picking first.
+	steps := []DraftStep{LastInitialPicks, LastCounterPick, LastFinalPick}
+	isP1 := []bool{true, false, true}
+	for i := range steps {
+		if NextStep(tournamentInfo, gameState) != steps[i] || IsP1PickNext(tournamentInfo, gameState) != isP1[i] {
+			t.Errorf("Expected %s by P1: %t at %+v", steps[i], isP1[i], gameState)
+		}
+		gameState = getSuccessors(tournamentInfo, gameState)[0]
+	}
+	if !draftIsComplete(tournamentInfo, gameState) {
+		t.Errorf("Expected the draft to be complete after three steps but got %+v", gameState)
+	}
+
+	value, line := TurinMinimax(tournamentInfo, GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}, true, -1.0, 2.0)
+	if !draftIsComplete(tournamentInfo, line) || !(math.Abs(value-GetMatchupValue(line.P3Round.Matchup, tournamentInfo)) < epsilon) {
+		t.Errorf("Expected a Bo1 to be worth its one game but got %f for %+v", value, line)
+	}
+}
+
+func TestSeriesStopsOnceDecided(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5, MatchupOdds: MatchupsV1d2}
+	gameState := GameState{
+		P2Rounds: []P2Round{
+			{Picks: []Faction{GC, TZ}, Matchup: Matchup{P1: GC, P2: KH}, WhoWon: P1},
+			{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}, WhoWon: P2},
+		},
+		P3Round: P3Round{},
+	}
+
+	// 1-1 in a Bo5 is still live, so the rest is drafted as if the first two games were certain.
+	value, line := TurinMinimax(tournamentInfo, gameState, IsP1PickNext(tournamentInfo, gameState), -1.0, 2.0)
+	expected := seriesWinRate([]float64{1.0, 0.0,
+		GetMatchupValue(line.P2Rounds[2].Matchup, tournamentInfo),
+		GetMatchupValue(line.P2Rounds[3].Matchup, tournamentInfo),
+		GetMatchupValue(line.P3Round.Matchup, tournamentInfo)})
+	if !(math.Abs(value-expected) < epsilon) {
+		t.Errorf("Expected the series to be worth %f given the results so far but got %f", expected, value)
+	}
+
+	// Three wins end it, however many games are left.
+	gameState.P2Rounds = append(gameState.P2Rounds,
+		P2Round{Picks: []Faction{KI, NG}, Matchup: Matchup{P1: KI, P2: SL}, WhoWon: P1},
+		P2Round{Picks: []Faction{NG, SL}, Matchup: Matchup{P1: KH, P2: NG}, WhoWon: P1})
+	if !draftIsComplete(tournamentInfo, gameState) || IsP1PickNext(tournamentInfo, gameState) {
+		t.Errorf("Expected the series to be over at 3-1 but got %+v", gameState)
+	}
+	if value, _ := TurinMinimax(tournamentInfo, gameState, true, -1.0, 2.0); value != 1.0 {
+		t.Errorf("Expected P1 to have won the series but got %f", value)
+	}
+}
+
+func TestInitialPicksExcludeFirstPickersPlays(t *testing.T) {
+	combosWithout := func(played ...Faction) map[string]bool {
+		combos := map[string]bool{}
+		for _, v := range getTwoCombos(defaultTournamentInfo, GameState{}, true) {
+			isPlayed := false
+			for _, p := range played {
+				isPlayed = isPlayed || v[0] == p || v[1] == p
+			}
+			if !isPlayed {
+				combos[fmt.Sprint(v)] = true
+			}
+		}
+		return combos
+	}
+
+	for name, test := range map[string]struct {
+		roundCount int
+		gameState  GameState
+		// What the first picker has played, which they can't offer again, and what the other player has played,
+		// which the first picker used to be kept from offering instead.
+		firstPickerPlayed []Faction
+		otherPlayed       []Faction
+	}{
+		"bo3 game 2": {3, GameState{P2Rounds: []P2Round{
+			{Picks: []Faction{TZ, SL}, Matchup: Matchup{P1: TZ, P2: GC}},
+		}}, []Faction{GC}, []Faction{TZ}},
+		"bo5 game 3": {5, GameState{P2Rounds: []P2Round{
+			{Picks: []Faction{TZ, SL}, Matchup: Matchup{P1: TZ, P2: GC}},
+			{Picks: []Faction{KH, OK}, Matchup: Matchup{P1: SL, P2: KH}},
+		}}, []Faction{TZ, SL}, []Faction{GC, KH}},
+	} {
+		tournamentInfo := TournamentInfo{RoundCount: test.roundCount, MatchupOdds: MatchupsV1d2}
+		successors := map[string]bool{}
+		for _, v := range getSuccessors(tournamentInfo, test.gameState) {
+			successors[fmt.Sprint(v.P2Rounds[len(v.P2Rounds)-1].Picks)] = true
+		}
+
+		expected := combosWithout(test.firstPickerPlayed...)
+		if !reflect.DeepEqual(successors, expected) {
+			t.Errorf("Expected the initial picks in %s to be %v but got %v", name, expected, successors)
+		}
+		if previous := combosWithout(test.otherPlayed...); reflect.DeepEqual(successors, previous) {
+			t.Errorf("Expected the initial picks in %s to differ from the old rule's %v", name, previous)
+		}
+	}
+}
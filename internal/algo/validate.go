@@ -0,0 +1,62 @@
+package algo
+
+import (
+	"fmt"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"strings"
+)
+
+/**
+IllegalDraftError is a draft that can't be reached by legal moves under its ruleset. Game counts from 1, and Step is the
+decision in it that breaks the rules, or GameResult if a result the rest of the draft depends on was left out. An
+empty Step means the draft goes on after it was already complete.
+*/
+type IllegalDraftError struct {
+	Game int
+	Step DraftStep
+}
+
+func (e *IllegalDraftError) Error() string {
+	step := strings.ReplaceAll(string(e.Step), "-", " ")
+	switch e.Step {
+	case "":
+		return "the draft goes on after it is already complete"
+	case GameResult:
+		return fmt.Sprintf("game %d's result has to be recorded before the draft can go on", e.Game)
+	case GlobalBan, MapBan:
+		return fmt.Sprintf("one of the %ss isn't legal", step)
+	}
+	return fmt.Sprintf("the %s in game %d isn't legal", step, e.Game)
+}
+
+/**
+Checks that gameState can be reached from the start of the draft by legal moves, by replaying it one decision at a
+time. The searches assume as much, and panic deep inside if it isn't so, so anything drafted by hand should be checked
+first. Returns an *IllegalDraftError if not.
+*/
+func ValidateGameState(tournamentInfo TournamentInfo, gameState GameState) error {
+	current := GameState{P2Rounds: []P2Round{}, P3Round: P3Round{}}
+	for !(isAncestor(gameState, current) && isAncestor(current, gameState)) {
+		if draftIsComplete(tournamentInfo, current) {
+			return &IllegalDraftError{}
+		}
+		if resultIsNext(tournamentInfo, current) {
+			// Results recorded in gameState are carried over as the draft is replayed, so this one was left out.
+			return &IllegalDraftError{Game: len(current.P2Rounds), Step: GameResult}
+		}
+		successors := getSuccessors(tournamentInfo, current)
+		next := -1
+		for i, v := range successors {
+			copyResults(gameState, v)
+			if isAncestor(v, gameState) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return &IllegalDraftError{Game: moveRound(tournamentInfo, current, successors[0]) + 1, Step: NextStep(tournamentInfo, current)}
+		}
+		current = successors[next]
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package algo
+
+import (
+	"errors"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"testing"
+)
+
+func TestValidateGameStateLegal(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	_, complete := TurinMinimax(tournamentInfo, GameState{}, true, -1.0, 2.0)
+
+	for name, gameState := range map[string]GameState{
+		"an empty draft": {},
+		"a partial round": {
+			P2Rounds: []P2Round{
+				{Picks: []Faction{TZ, SL}, Matchup: Matchup{P1: TZ, P2: GC}, WhoWon: P2},
+				{Picks: []Faction{KH, TZ}},
+			},
+		},
+		"a complete draft": complete,
+		"a series decided early": {
+			P2Rounds: []P2Round{
+				{Picks: []Faction{TZ, SL}, Matchup: Matchup{P1: TZ, P2: GC}, WhoWon: P2},
+				{Picks: []Faction{KH, OK}, Matchup: Matchup{P1: NG, P2: KH}, WhoWon: P2},
+			},
+		},
+	} {
+		if err := ValidateGameState(tournamentInfo, gameState); err != nil {
+			t.Errorf("Expected %s to be legal but got %s", name, err)
+		}
+	}
+}
+
+func TestValidateGameStateIllegal(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	for name, test := range map[string]struct {
+		gameState GameState
+		expected  IllegalDraftError
+	}{
+		"an unknown faction": {
+			GameState{P2Rounds: []P2Round{{Picks: []Faction{SL, "XX"}}}},
+			IllegalDraftError{Game: 1, Step: InitialPicks},
+		},
+		"a final pick that wasn't offered": {
+			GameState{P2Rounds: []P2Round{{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: KH, P2: GC}}}},
+			IllegalDraftError{Game: 1, Step: FinalPick},
+		},
+		"a final pick before the counter pick": {
+			GameState{P2Rounds: []P2Round{{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ}}}},
+			IllegalDraftError{Game: 1, Step: CounterPick},
+		},
+		"a repeated faction": {
+			GameState{P2Rounds: []P2Round{
+				{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+				{Picks: []Faction{KH, GC}, Matchup: Matchup{P1: SL}},
+			}},
+			IllegalDraftError{Game: 2, Step: InitialPicks},
+		},
+		"a game too many": {
+			GameState{
+				P2Rounds: []P2Round{
+					{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+					{Picks: []Faction{KH, TZ}, Matchup: Matchup{P1: OK, P2: KH}},
+					{Picks: []Faction{GC, KI}, Matchup: Matchup{P1: GC, P2: KI}},
+				},
+				P3Round: P3Round{Picks: []Faction{GC, KI, NG}, Ban: SL, CounterBan: GC, Matchup: Matchup{P1: KI, P2: NG}},
+			},
+			IllegalDraftError{},
+		},
+	} {
+		err := ValidateGameState(tournamentInfo, test.gameState)
+		var illegal *IllegalDraftError
+		if !errors.As(err, &illegal) {
+			t.Errorf("Expected %s to be illegal but got %v", name, err)
+			continue
+		}
+		if *illegal != test.expected {
+			t.Errorf("Expected %s to break the rules at %+v but got %+v", name, test.expected, *illegal)
+		}
+	}
+}
+
+func TestValidateGameStateMissingResult(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2LoserPicks}
+	gameState := GameState{P2Rounds: []P2Round{
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}},
+		{Picks: []Faction{KH, TZ}},
+	}}
+
+	err := ValidateGameState(tournamentInfo, gameState)
+	expected := &IllegalDraftError{Game: 1, Step: GameResult}
+	var illegal *IllegalDraftError
+	if !errors.As(err, &illegal) || *illegal != *expected {
+		t.Errorf("Expected %v but got %v", expected, err)
+	}
+
+	gameState.P2Rounds[0].WhoWon = P2
+	if err := ValidateGameState(tournamentInfo, gameState); err != nil {
+		t.Errorf("Expected the draft to be legal once the result is in but got %s", err)
+	}
+}
@@ -0,0 +1,442 @@
+package bracket
+
+import (
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math/rand"
+	"sort"
+)
+
+type Format string
+
+const (
+	SingleElimination Format = "single-elimination"
+	DoubleElimination Format = "double-elimination"
+	Swiss             Format = "swiss"
+)
+
+// Player is an entrant along with how well they play each faction.
+type Player struct {
+	Name string
+	// FactionStrength is the player's skill on each faction in Elo points relative to an average player. Lords
+	// without a strength of their own use their race's, and anything else missing counts as 0.
+	FactionStrength map[Faction]float64
+}
+
+// Bracket is the structure of an event.
+type Bracket struct {
+	Format Format
+	// Players in seed order, top seed first. The better seed is P1 in every match.
+	Players []Player
+	// SwissRounds and TopCut only apply to Swiss: how many rounds are played and how many players advance.
+	SwissRounds int
+	TopCut      int
+}
+
+// PlayerOdds is how a player fared across every simulated run of an event.
+type PlayerOdds struct {
+	Name     string
+	Champion float64
+	// Finalist is the chance of playing in the final. Elimination formats only.
+	Finalist float64
+	// ReachedRound[i] is the chance of playing in round i + 1. Single elimination only.
+	ReachedRound []float64
+	// TopCut is the chance of finishing in the top cut. Swiss only.
+	TopCut        float64
+	MeanMatchWins float64
+}
+
+/**
+Simulator plays an event out many times over with a draft solver as the model for every match.
+
+Each match's series win probability comes from solving the empty draft between the two players, with the
+tournament's matchup odds shifted by the players' strengths on each faction. Those are computed once per pair and
+reused across runs.
+*/
+type Simulator struct {
+	TournamentInfo TournamentInfo
+	// Solver drafts each match. MinimaxSolver if unset.
+	Solver     algo.Solver
+	Iterations int
+	Seed       int64
+
+	seriesOdds map[[2]int]float64
+}
+
+const defaultIterations = 10000
+
+// Tally of a single run, indexed by seed.
+type runResult struct {
+	champion     int
+	finalists    []int
+	roundReached []int
+	topCut       []int
+	matchWins    []int
+}
+
+func (s *Simulator) Simulate(bracket Bracket) ([]PlayerOdds, error) {
+	playerCount := len(bracket.Players)
+	if playerCount < 2 {
+		return nil, fmt.Errorf("need at least 2 players but got %d", playerCount)
+	}
+	if bracket.Format == Swiss && (bracket.SwissRounds < 1 || bracket.TopCut < 1 || bracket.TopCut > playerCount) {
+		return nil, fmt.Errorf("swiss needs at least one round and a top cut between 1 and %d players", playerCount)
+	}
+	if err := s.TournamentInfo.Validate(); err != nil {
+		return nil, err
+	}
+
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = defaultIterations
+	}
+	s.seriesOdds = map[[2]int]float64{}
+	rng := rand.New(rand.NewSource(s.Seed))
+
+	odds := make([]PlayerOdds, playerCount)
+	for i, v := range bracket.Players {
+		odds[i].Name = v.Name
+	}
+	for n := 0; n < iterations; n++ {
+		var result runResult
+		switch bracket.Format {
+		case SingleElimination:
+			result = s.runSingleElimination(bracket, rng)
+		case DoubleElimination:
+			result = s.runDoubleElimination(bracket, rng)
+		case Swiss:
+			result = s.runSwiss(bracket, rng)
+		default:
+			return nil, fmt.Errorf("unknown format: %s", bracket.Format)
+		}
+
+		odds[result.champion].Champion++
+		for _, v := range result.finalists {
+			odds[v].Finalist++
+		}
+		for _, v := range result.topCut {
+			odds[v].TopCut++
+		}
+		for i, rounds := range result.roundReached {
+			for len(odds[i].ReachedRound) < rounds {
+				odds[i].ReachedRound = append(odds[i].ReachedRound, 0)
+			}
+			for r := 0; r < rounds; r++ {
+				odds[i].ReachedRound[r]++
+			}
+		}
+		for i, v := range result.matchWins {
+			odds[i].MeanMatchWins += float64(v)
+		}
+	}
+
+	for i := range odds {
+		odds[i].Champion /= float64(iterations)
+		odds[i].Finalist /= float64(iterations)
+		odds[i].TopCut /= float64(iterations)
+		odds[i].MeanMatchWins /= float64(iterations)
+		for r := range odds[i].ReachedRound {
+			odds[i].ReachedRound[r] /= float64(iterations)
+		}
+	}
+	return odds, nil
+}
+
+/**
+Plays one match between two seeds and returns the winner. The better seed is P1.
+*/
+func (s *Simulator) playMatch(bracket Bracket, a int, b int, rng *rand.Rand, result *runResult) int {
+	p1, p2 := a, b
+	if b < a {
+		p1, p2 = b, a
+	}
+	winner := p2
+	key := [2]int{p1, p2}
+	if _, ok := s.seriesOdds[key]; !ok {
+		s.seriesOdds[key] = s.SeriesWinProbability(bracket.Players[p1], bracket.Players[p2])
+	}
+	if rng.Float64() < s.seriesOdds[key] {
+		winner = p1
+	}
+	result.matchWins[winner]++
+	return winner
+}
+
+/**
+The odds of p1 beating p2 in a series with p1 as P1, from solving their draft.
+*/
+func (s *Simulator) SeriesWinProbability(p1 Player, p2 Player) float64 {
+	solver := s.Solver
+	if solver == nil {
+		solver = algo.MinimaxSolver{}
+	}
+	tournamentInfo := TournamentInfo{
+		RoundCount:  s.TournamentInfo.RoundCount,
+		MatchupOdds: PlayerMatchupOdds(s.TournamentInfo, p1, p2),
+		Ruleset:     s.TournamentInfo.Ruleset,
+	}
+	odds, _ := solver.Solve(tournamentInfo, algo.GameState{P2Rounds: []algo.P2Round{}, P3Round: algo.P3Round{}}, true)
+	return odds
+}
+
+func (p Player) strength(faction Faction) float64 {
+	if strength, ok := p.FactionStrength[faction]; ok {
+		return strength
+	}
+	return p.FactionStrength[Race(faction)]
+}
+
+/**
+PlayerMatchupOdds is every ordered matchup of the ruleset's pool for a particular pair of players, on every map that has
+odds of its own as well as without a map.
+*/
+func PlayerMatchupOdds(tournamentInfo TournamentInfo, p1 Player, p2 Player) map[Matchup]float64 {
+	gameMaps := map[GameMap]bool{"": true}
+	for k := range tournamentInfo.MatchupOdds {
+		gameMaps[k.Map] = true
+	}
+	pool := tournamentInfo.Ruleset.Pool()
+	matchupOdds := map[Matchup]float64{}
+	for gameMap := range gameMaps {
+		for _, f1 := range pool {
+			for _, f2 := range pool {
+				matchup := Matchup{P1: f1, P2: f2, Map: gameMap}
+				strengthDiff := p1.strength(f1) - p2.strength(f2)
+				matchupOdds[matchup] = AdjustForStrength(GetMatchupValue(matchup, tournamentInfo), strengthDiff)
+			}
+		}
+	}
+	return matchupOdds
+}
+
+func newRunResult(playerCount int) runResult {
+	return runResult{
+		roundReached: make([]int, playerCount),
+		matchWins:    make([]int, playerCount),
+	}
+}
+
+func (s *Simulator) runSingleElimination(bracket Bracket, rng *rand.Rand) runResult {
+	result := newRunResult(len(bracket.Players))
+	// -1 marks a bye.
+	var positions []int
+	for _, seed := range seedOrder(len(bracket.Players)) {
+		if seed < len(bracket.Players) {
+			positions = append(positions, seed)
+		} else {
+			positions = append(positions, -1)
+		}
+	}
+
+	for round := 1; len(positions) > 1; round++ {
+		if len(positions) == 2 {
+			result.finalists = []int{positions[0], positions[1]}
+		}
+		var next []int
+		for i := 0; i < len(positions); i += 2 {
+			a, b := positions[i], positions[i+1]
+			for _, v := range []int{a, b} {
+				if v != -1 {
+					result.roundReached[v] = round
+				}
+			}
+			switch {
+			case a == -1:
+				next = append(next, b)
+			case b == -1:
+				next = append(next, a)
+			default:
+				next = append(next, s.playMatch(bracket, a, b, rng, &result))
+			}
+		}
+		positions = next
+	}
+	result.champion = positions[0]
+	return result
+}
+
+/**
+Winners and losers brackets are paired in seed order each round, with a bye for the best seed left over when a bracket
+has an odd number of players. The losers bracket winner has to beat the winners bracket winner twice in the grand
+final.
+*/
+func (s *Simulator) runDoubleElimination(bracket Bracket, rng *rand.Rand) runResult {
+	result := newRunResult(len(bracket.Players))
+	var winners []int
+	for i := range bracket.Players {
+		winners = append(winners, i)
+	}
+	var losers []int
+
+	for len(winners) > 1 || len(losers) > 1 {
+		var nextWinners, dropped []int
+		if len(winners) > 1 {
+			nextWinners, dropped = s.playRound(bracket, winners, rng, &result)
+		} else {
+			nextWinners = winners
+		}
+		var nextLosers []int
+		if len(losers) > 1 {
+			nextLosers, _ = s.playRound(bracket, losers, rng, &result)
+		} else {
+			nextLosers = losers
+		}
+		winners = nextWinners
+		losers = append(nextLosers, dropped...)
+		sort.Ints(losers)
+	}
+
+	if len(losers) == 0 {
+		result.champion = winners[0]
+		return result
+	}
+	result.finalists = []int{winners[0], losers[0]}
+	champion := s.playMatch(bracket, winners[0], losers[0], rng, &result)
+	if champion == losers[0] {
+		// Bracket reset - both players now have one loss.
+		champion = s.playMatch(bracket, winners[0], losers[0], rng, &result)
+	}
+	result.champion = champion
+	return result
+}
+
+/**
+Pairs sorted seeds off in order, returning who won and who lost. An odd player out gets a bye.
+*/
+func (s *Simulator) playRound(bracket Bracket, seeds []int, rng *rand.Rand, result *runResult) ([]int, []int) {
+	var winners, losers []int
+	start := 0
+	if len(seeds)%2 == 1 {
+		winners = append(winners, seeds[0])
+		start = 1
+	}
+	for i := start; i < len(seeds); i += 2 {
+		winner := s.playMatch(bracket, seeds[i], seeds[i+1], rng, result)
+		loser := seeds[i]
+		if winner == loser {
+			loser = seeds[i+1]
+		}
+		winners = append(winners, winner)
+		losers = append(losers, loser)
+	}
+	sort.Ints(winners)
+	return winners, losers
+}
+
+func (s *Simulator) runSwiss(bracket Bracket, rng *rand.Rand) runResult {
+	playerCount := len(bracket.Players)
+	result := newRunResult(playerCount)
+	played := map[[2]int]bool{}
+	hadBye := map[int]bool{}
+	opponents := make([][]int, playerCount)
+
+	for round := 0; round < bracket.SwissRounds; round++ {
+		standings := swissOrder(result.matchWins, opponents)
+		pairings, bye := PairSwiss(standings, played, hadBye)
+		if bye != -1 {
+			hadBye[bye] = true
+			result.matchWins[bye]++
+		}
+		for _, v := range pairings {
+			played[[2]int{v[0], v[1]}] = true
+			played[[2]int{v[1], v[0]}] = true
+			opponents[v[0]] = append(opponents[v[0]], v[1])
+			opponents[v[1]] = append(opponents[v[1]], v[0])
+			s.playMatch(bracket, v[0], v[1], rng, &result)
+		}
+	}
+
+	standings := swissOrder(result.matchWins, opponents)
+	result.champion = standings[0]
+	result.topCut = standings[:bracket.TopCut]
+	return result
+}
+
+/**
+Ranks players by match wins, then by Buchholz (their opponents' match wins summed), then by seed.
+*/
+func swissOrder(matchWins []int, opponents [][]int) []int {
+	buchholz := make([]int, len(matchWins))
+	order := make([]int, len(matchWins))
+	for i := range matchWins {
+		order[i] = i
+		for _, v := range opponents[i] {
+			buchholz[i] += matchWins[v]
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if matchWins[a] != matchWins[b] {
+			return matchWins[a] > matchWins[b]
+		}
+		return buchholz[a] > buchholz[b]
+	})
+	return order
+}
+
+/**
+Pairs a Swiss round from standings, best first. With an odd number of players the lowest ranked player who hasn't had
+a bye yet sits out, returned as the second value (-1 if nobody does). Players are paired top down with the nearest
+player below them they haven't met, backtracking when that leaves someone stranded. If no pairing avoids rematches at
+all, standings are simply paired in order.
+*/
+func PairSwiss(standings []int, played map[[2]int]bool, hadBye map[int]bool) ([][2]int, int) {
+	remaining := append([]int{}, standings...)
+	bye := -1
+	if len(remaining)%2 == 1 {
+		byeIndex := len(remaining) - 1
+		for i := len(remaining) - 1; i >= 0; i-- {
+			if !hadBye[remaining[i]] {
+				byeIndex = i
+				break
+			}
+		}
+		bye = remaining[byeIndex]
+		remaining = append(remaining[:byeIndex], remaining[byeIndex+1:]...)
+	}
+
+	if pairings, ok := pairWithoutRematches(remaining, played); ok {
+		return pairings, bye
+	}
+	var pairings [][2]int
+	for i := 0; i < len(remaining); i += 2 {
+		pairings = append(pairings, [2]int{remaining[i], remaining[i+1]})
+	}
+	return pairings, bye
+}
+
+func pairWithoutRematches(remaining []int, played map[[2]int]bool) ([][2]int, bool) {
+	if len(remaining) == 0 {
+		return [][2]int{}, true
+	}
+	top := remaining[0]
+	for i := 1; i < len(remaining); i++ {
+		opponent := remaining[i]
+		if played[[2]int{top, opponent}] {
+			continue
+		}
+		rest := append(append([]int{}, remaining[1:i]...), remaining[i+1:]...)
+		if pairings, ok := pairWithoutRematches(rest, played); ok {
+			return append([][2]int{{top, opponent}}, pairings...), true
+		}
+	}
+	return nil, false
+}
+
+/**
+Standard bracket positions for playerCount seeds, padded to a power of two, so that the top seeds meet as late as
+possible: 1 v 8, 4 v 5, 2 v 7, 3 v 6 and so on. Seeds are zero based and those past playerCount are byes.
+*/
+func seedOrder(playerCount int) []int {
+	order := []int{0}
+	for len(order) < playerCount {
+		size := len(order) * 2
+		var next []int
+		for _, v := range order {
+			next = append(next, v, size-1-v)
+		}
+		order = next
+	}
+	return order
+}
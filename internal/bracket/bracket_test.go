@@ -0,0 +1,215 @@
+package bracket
+
+import (
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"reflect"
+	"testing"
+)
+
+const epsilon = .000001
+
+// Monte Carlo results should land this close to the true odds.
+const samplingTolerance = .03
+
+// The pool heuristic solves a draft instantly, which is all these tests need.
+var fastSolver = algo.DepthLimitedSolver{Depth: 0}
+
+func evenPlayers(count int) []Player {
+	var players []Player
+	for i := 0; i < count; i++ {
+		players = append(players, Player{Name: string(rune('A' + i)), FactionStrength: map[Faction]float64{}})
+	}
+	return players
+}
+
+func allFactions(strength float64) map[Faction]float64 {
+	strengths := map[Faction]float64{}
+	for f := range Factions {
+		strengths[f] = strength
+	}
+	return strengths
+}
+
+func newSimulator(seed int64) *Simulator {
+	return &Simulator{
+		TournamentInfo: TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2},
+		Solver:         fastSolver,
+		Iterations:     4000,
+		Seed:           seed,
+	}
+}
+
+func TestAdjustForStrength(t *testing.T) {
+	if !(math.Abs(AdjustForStrength(.6, 0)-.6) < epsilon) {
+		t.Errorf("Expected no strength difference to leave odds alone")
+	}
+	if !(math.Abs(AdjustForStrength(.5, EloScale)-10.0/11.0) < epsilon) {
+		t.Errorf("Expected %f Elo to make an even matchup 10 to 1 but got %f", EloScale, AdjustForStrength(.5, EloScale))
+	}
+	if !(math.Abs(AdjustForStrength(.7, 150)+AdjustForStrength(.3, -150)-1.0) < epsilon) {
+		t.Errorf("Expected adjusting both sides of a matchup to keep them summing to 1")
+	}
+	if AdjustForStrength(1.0, -1000) != 1.0 || AdjustForStrength(0.0, 1000) != 0.0 {
+		t.Errorf("Expected certain matchups to stay certain")
+	}
+}
+
+func TestSeriesWinProbabilityFavoursStrongerPlayer(t *testing.T) {
+	players := []Player{{Name: "Strong", FactionStrength: allFactions(200)}, {Name: "Weak", FactionStrength: allFactions(0)}}
+	simulator := newSimulator(1)
+	even := simulator.SeriesWinProbability(Player{}, Player{})
+	strong := simulator.SeriesWinProbability(players[0], players[1])
+	weak := simulator.SeriesWinProbability(players[1], players[0])
+	if !(strong > even && weak < even) {
+		t.Errorf("Expected the stronger player to be favoured either way round but got %f and %f against %f", strong, weak, even)
+	}
+}
+
+func TestSeriesWinProbabilityUsesSolver(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2}
+	simulator := &Simulator{TournamentInfo: tournamentInfo}
+	expected, _ := algo.TurinMinimax(tournamentInfo, algo.GameState{P2Rounds: []algo.P2Round{}, P3Round: algo.P3Round{}}, true, -1.0, 2.0)
+	actual := simulator.SeriesWinProbability(Player{}, Player{})
+	if !(math.Abs(expected-actual) < epsilon) {
+		t.Errorf("Expected even players to get the plain minimax value %f but got %f", expected, actual)
+	}
+}
+
+func TestSingleEliminationEvenField(t *testing.T) {
+	odds, err := newSimulator(1).Simulate(Bracket{Format: SingleElimination, Players: evenPlayers(8)})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	total := 0.0
+	for _, v := range odds {
+		total += v.Champion
+		if !(math.Abs(v.ReachedRound[0]-1.0) < epsilon) {
+			t.Errorf("Expected %s to always play round 1", v.Name)
+		}
+		if len(v.ReachedRound) != 3 {
+			t.Errorf("Expected 3 rounds for 8 players but got %d", len(v.ReachedRound))
+		}
+		// Even players still aren't quite 50-50 because the better seed is always P1.
+		if !(math.Abs(v.Champion-.125) < samplingTolerance*2) {
+			t.Errorf("Expected %s to win about 1 in 8 events but got %f", v.Name, v.Champion)
+		}
+	}
+	if !(math.Abs(total-1.0) < epsilon) {
+		t.Errorf("Expected champion odds to sum to 1 but got %f", total)
+	}
+}
+
+func TestSingleEliminationByes(t *testing.T) {
+	odds, err := newSimulator(1).Simulate(Bracket{Format: SingleElimination, Players: evenPlayers(3)})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	// The top seed sits out round 1 and goes straight to the final.
+	if !(math.Abs(odds[0].Finalist-1.0) < epsilon) {
+		t.Errorf("Expected the top seed to always reach the final but got %f", odds[0].Finalist)
+	}
+	if !(math.Abs(odds[1].Finalist+odds[2].Finalist-1.0) < epsilon) {
+		t.Errorf("Expected one of the other seeds in every final")
+	}
+}
+
+func TestDominantPlayerWins(t *testing.T) {
+	players := evenPlayers(6)
+	players[5].FactionStrength = allFactions(2000)
+	brackets := []Bracket{
+		{Format: SingleElimination, Players: players},
+		{Format: DoubleElimination, Players: players},
+		{Format: Swiss, Players: players, SwissRounds: 3, TopCut: 2},
+	}
+	for _, bracket := range brackets {
+		odds, err := newSimulator(3).Simulate(bracket)
+		if err != nil {
+			t.Fatalf("Expected no error but got %s", err)
+		}
+		if !(odds[5].Champion > .99) {
+			t.Errorf("Expected the dominant player to win %s but they won %f of the time", bracket.Format, odds[5].Champion)
+		}
+	}
+}
+
+func TestDoubleEliminationEvenField(t *testing.T) {
+	odds, err := newSimulator(5).Simulate(Bracket{Format: DoubleElimination, Players: evenPlayers(5)})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	champion, finalist := 0.0, 0.0
+	for _, v := range odds {
+		champion += v.Champion
+		finalist += v.Finalist
+	}
+	if !(math.Abs(champion-1.0) < epsilon) || !(math.Abs(finalist-2.0) < epsilon) {
+		t.Errorf("Expected one champion and two finalists per event but got %f and %f", champion, finalist)
+	}
+}
+
+func TestSwissTopCut(t *testing.T) {
+	odds, err := newSimulator(9).Simulate(Bracket{Format: Swiss, Players: evenPlayers(7), SwissRounds: 3, TopCut: 4})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	topCut, matchWins := 0.0, 0.0
+	for _, v := range odds {
+		topCut += v.TopCut
+		matchWins += v.MeanMatchWins
+	}
+	if !(math.Abs(topCut-4.0) < epsilon) {
+		t.Errorf("Expected 4 players to make the cut per event but got %f", topCut)
+	}
+	// 3 matches and a bye every round.
+	if !(math.Abs(matchWins-12.0) < epsilon) {
+		t.Errorf("Expected 12 match wins per event but got %f", matchWins)
+	}
+}
+
+func TestSameSeedSameResult(t *testing.T) {
+	bracket := Bracket{Format: DoubleElimination, Players: evenPlayers(6)}
+	first, _ := newSimulator(11).Simulate(bracket)
+	second, _ := newSimulator(11).Simulate(bracket)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same seed to give the same odds")
+	}
+}
+
+func TestInvalidBrackets(t *testing.T) {
+	brackets := []Bracket{
+		{Format: SingleElimination, Players: evenPlayers(1)},
+		{Format: Swiss, Players: evenPlayers(4), SwissRounds: 0, TopCut: 2},
+		{Format: Swiss, Players: evenPlayers(4), SwissRounds: 2, TopCut: 5},
+		{Format: "round-robin", Players: evenPlayers(4)},
+	}
+	for _, bracket := range brackets {
+		if _, err := newSimulator(1).Simulate(bracket); err == nil {
+			t.Errorf("Expected an error for %+v", bracket)
+		}
+	}
+}
+
+func TestPairSwissAvoidsRematches(t *testing.T) {
+	played := map[[2]int]bool{{0, 1}: true, {1, 0}: true, {2, 3}: true, {3, 2}: true}
+	pairings, bye := PairSwiss([]int{0, 1, 2, 3}, played, map[int]bool{})
+	expected := [][2]int{{0, 2}, {1, 3}}
+	if bye != -1 || !reflect.DeepEqual(pairings, expected) {
+		t.Errorf("Expected %v with no bye but got %v and %d", expected, pairings, bye)
+	}
+}
+
+func TestPairSwissBye(t *testing.T) {
+	pairings, bye := PairSwiss([]int{0, 1, 2}, map[[2]int]bool{}, map[int]bool{2: true})
+	if bye != 1 || !reflect.DeepEqual(pairings, [][2]int{{0, 2}}) {
+		t.Errorf("Expected seed 1 to get the bye since 2 already had one but got %v and %d", pairings, bye)
+	}
+}
+
+func TestSeedOrder(t *testing.T) {
+	expected := []int{0, 7, 3, 4, 1, 6, 2, 5}
+	if !reflect.DeepEqual(seedOrder(8), expected) {
+		t.Errorf("Expected %v but got %v", expected, seedOrder(8))
+	}
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/**
+Cache keeps search results in memory, dropping the least recently used once it holds size of them. With a directory
+it also keeps every result on disk, so they outlive the process and anything dropped from memory can be read back.
+
+Results are stored as JSON, so anything that round trips through encoding/json can be cached. A Cache is safe for
+concurrent use.
+*/
+type Cache struct {
+	size  int
+	dir   string
+	mu    sync.Mutex
+	order *list.List
+	// entries points into order, which runs from the most to the least recently used.
+	entries map[string]*list.Element
+	stats   Stats
+}
+
+type entry struct {
+	key  string
+	data []byte
+}
+
+// Stats counts how a cache has been used since it was made.
+type Stats struct {
+	Hits      int
+	DiskHits  int
+	Misses    int
+	Evictions int
+	Entries   int
+	Size      int
+}
+
+/**
+A cache of up to size results in memory, also kept under dir unless it is empty. A size of zero or less keeps nothing
+in memory.
+*/
+func New(size int, dir string) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{size: size, dir: dir, order: list.New(), entries: map[string]*list.Element{}}, nil
+}
+
+/**
+Reads the result stored under key into value, and reports whether there was one.
+*/
+func (c *Cache) Get(key string, value interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		if json.Unmarshal(element.Value.(*entry).data, value) == nil {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			return true
+		}
+	}
+	if c.dir != "" {
+		data, err := os.ReadFile(c.path(key))
+		if err == nil && json.Unmarshal(data, value) == nil {
+			c.remember(key, data)
+			c.stats.DiskHits++
+			return true
+		}
+	}
+	c.stats.Misses++
+	return false
+}
+
+/**
+Stores value under key, replacing whatever was there.
+*/
+func (c *Cache) Put(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remember(key, data)
+	if c.dir == "" {
+		return nil
+	}
+	// Written to a temporary file first so that a crash mid write can't leave a truncated result behind.
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path(key))
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Size = c.size
+	return stats
+}
+
+/**
+Keeps data in memory as the most recently used entry, evicting the least recently used if that makes too many.
+*/
+func (c *Cache) remember(key string, data []byte) {
+	if c.size <= 0 {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).data = data
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, data: data})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+/**
+The key for a search from gameState under tournamentInfo. search tells apart different searches of the same position,
+e.g. a recommendation from a review, or searches for different objectives.
+
+Drafts that only differ in how they were written down, such as an empty list of bans against none at all or the same
+player pool in another order, share a key.
+Matchup odds go in as a hash, so keys stay short however many odds there are.
+*/
+func Key(tournamentInfo TournamentInfo, gameState algo.GameState, search string) (string, error) {
+	ruleset, err := json.Marshal(tournamentInfo.Ruleset.Canonical())
+	if err != nil {
+		return "", err
+	}
+	draft, err := json.Marshal(algo.CanonicalGameState(gameState))
+	if err != nil {
+		return "", err
+	}
+	pools, err := json.Marshal([][]Faction{tournamentInfo.PlayerPool(true), tournamentInfo.PlayerPool(false)})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "rounds %d\nruleset %s\npools %s\nodds %s\ndraft %s\nsearch %s\n", tournamentInfo.RoundCount, ruleset, pools, OddsHash(tournamentInfo.MatchupOdds), draft, search)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"reflect"
+	"testing"
+)
+
+var tournamentInfo = TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2}
+
+func TestLeastRecentlyUsedIsEvicted(t *testing.T) {
+	cache, _ := New(2, "")
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	var value int
+	cache.Get("a", &value)
+	// b is now the least recently used.
+	cache.Put("c", 3)
+
+	if cache.Get("b", &value) {
+		t.Errorf("Expected b to have been evicted")
+	}
+	if !cache.Get("a", &value) || value != 1 || !cache.Get("c", &value) || value != 3 {
+		t.Errorf("Expected a and c to be kept")
+	}
+	expected := Stats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2, Size: 2}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v but got %+v", expected, stats)
+	}
+}
+
+func TestDiskOutlivesMemory(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := New(1, dir)
+	if err != nil {
+		t.Fatalf("Expected a cache but got %s", err)
+	}
+	line := algo.GameState{P2Rounds: []algo.P2Round{{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: TZ}, WhoWon: P1}}}
+	cache.Put("line", line)
+	cache.Put("other", 2)
+
+	var actual algo.GameState
+	if !cache.Get("line", &actual) || !reflect.DeepEqual(actual.P2Rounds, line.P2Rounds) {
+		t.Errorf("Expected the evicted line to be read back from disk but got %+v", actual)
+	}
+	reopened, _ := New(1, dir)
+	if !reopened.Get("other", new(int)) || reopened.Stats().DiskHits != 1 {
+		t.Errorf("Expected a new cache on the same directory to find results from the last one")
+	}
+}
+
+func TestKey(t *testing.T) {
+	gameState := algo.GameState{P2Rounds: []algo.P2Round{{Picks: []Faction{GC, KH}}}}
+	key, _ := Key(tournamentInfo, gameState, "recommend")
+
+	written := gameState
+	written.P1Bans = []Faction{}
+	written.MapBans = []GameMap{}
+	copiedOdds := tournamentInfo
+	copiedOdds.MatchupOdds = map[Matchup]float64{}
+	for k, v := range MatchupsV1d2 {
+		copiedOdds.MatchupOdds[k] = v
+	}
+	if actual, _ := Key(copiedOdds, written, "recommend"); actual != key {
+		t.Errorf("Expected the same draft and odds written differently to share a key")
+	}
+	pooled := tournamentInfo
+	pooled.P1Pool = []Faction{KH, GC, KI, NG, OK}
+	pooledKey, _ := Key(pooled, gameState, "recommend")
+	reordered := pooled
+	reordered.P1Pool = []Faction{GC, KH, KI, NG, OK}
+	if actual, _ := Key(reordered, gameState, "recommend"); actual != pooledKey {
+		t.Errorf("Expected the same pool in another order to share a key")
+	}
+
+	otherOdds := copiedOdds
+	otherOdds.MatchupOdds = map[Matchup]float64{Matchup{P1: GC, P2: KH}: .9}
+	longer := tournamentInfo
+	longer.RoundCount = 5
+	picked := algo.GameState{P2Rounds: []algo.P2Round{{Picks: []Faction{GC, KH}, Matchup: Matchup{P2: KI}}}}
+	oddsKey, _ := Key(otherOdds, gameState, "recommend")
+	roundsKey, _ := Key(longer, gameState, "recommend")
+	draftKey, _ := Key(tournamentInfo, picked, "recommend")
+	searchKey, _ := Key(tournamentInfo, gameState, "review")
+	for name, actual := range map[string]string{"odds": oddsKey, "rounds": roundsKey, "draft": draftKey, "search": searchKey, "pool": pooledKey} {
+		if actual == key {
+			t.Errorf("Expected a different %s to change the key", name)
+		}
+	}
+}
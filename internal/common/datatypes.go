@@ -1,6 +1,12 @@
 package common
 
-import "fmt"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
 
 type Faction string
 
@@ -24,9 +30,37 @@ var Factions = map[Faction]bool{
 	SL: true,
 	TZ: true}
 
+// GameMap is a battle map that a game can be played on.
+type GameMap string
+
 type Matchup struct {
 	P1 Faction
 	P2 Faction
+	// Map is where the game is played. Matchup odds without a map apply to every map that has no odds of its own.
+	Map GameMap
+}
+
+/**
+Matchups are written as P1-P2, e.g. GC-KH, or as GC-KH@Map on a particular map, so that they can key JSON objects.
+*/
+func (m Matchup) MarshalText() ([]byte, error) {
+	text := string(m.P1) + "-" + string(m.P2)
+	if m.Map != "" {
+		text += "@" + string(m.Map)
+	}
+	return []byte(text), nil
+}
+
+func (m *Matchup) UnmarshalText(text []byte) error {
+	factionText, gameMap, _ := strings.Cut(string(text), "@")
+	factions := strings.Split(factionText, "-")
+	if len(factions) != 2 {
+		return fmt.Errorf("matchup should look like P1-P2 or P1-P2@Map but got: %s", text)
+	}
+	m.P1 = Faction(factions[0])
+	m.P2 = Faction(factions[1])
+	m.Map = GameMap(gameMap)
+	return nil
 }
 
 type WhoWon string
@@ -40,58 +74,222 @@ const (
 type TournamentInfo struct {
 	RoundCount  int
 	MatchupOdds map[Matchup]float64
+	Ruleset     Ruleset
+	// P1Pool and P2Pool narrow down what each player can draft, e.g. to the factions their team gave them in a
+	// league. A player can draft the ruleset's whole pool if theirs is empty.
+	P1Pool []Faction
+	P2Pool []Faction
+}
+
+/**
+What a player can draft over the series, sorted.
+*/
+func (t TournamentInfo) PlayerPool(isP1 bool) []Faction {
+	pool := t.P2Pool
+	if isP1 {
+		pool = t.P1Pool
+	}
+	if len(pool) == 0 {
+		return t.Ruleset.Pool()
+	}
+	sorted := append([]Faction{}, pool...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// MaxRoundCount is the longest series that can be drafted, a Bo9.
+const MaxRoundCount = 9
+
+/**
+Series are best of an odd number of games, from a Bo1 up to a Bo9, so that one player always gets to a majority.
+*/
+func ValidateRoundCount(roundCount int) error {
+	if roundCount < 1 || roundCount > MaxRoundCount || roundCount%2 == 0 {
+		return fmt.Errorf("series must be best of an odd number of games from 1 to %d but got %d", MaxRoundCount, roundCount)
+	}
+	return nil
+}
+
+/**
+How many factions each player's pool needs to draft the whole series, whatever happens along the way. The first picker
+of the final game has to be able to make their offer on top of everything they played before it, unless they can play
+that again.
+*/
+func (t TournamentInfo) FactionsNeeded() int {
+	perGame, finalOffer := 1, 3
+	if t.Ruleset.IsTeamDraft() {
+		perGame, finalOffer = t.Ruleset.TeamSize, t.Ruleset.TeamSize+1
+	}
+	needed := finalOffer + t.Ruleset.GlobalBansPerPlayer
+	if t.Ruleset.Repeats != AllowRepeats || t.Ruleset.WinnerLockedOut {
+		needed += perGame * (t.RoundCount - 1)
+	}
+	return needed
+}
+
+/**
+Checks that the series is a valid length and that each player's pool lists each faction once and is deep enough to
+draft all of it, whatever happens along the way.
+*/
+func (t TournamentInfo) Validate() error {
+	if err := ValidateRoundCount(t.RoundCount); err != nil {
+		return err
+	}
+	needed := t.FactionsNeeded()
+	for _, isP1 := range []bool{true, false} {
+		pool := t.PlayerPool(isP1)
+		// Pools are sorted, so a faction listed twice is listed twice in a row.
+		for i := 1; i < len(pool); i++ {
+			if pool[i] == pool[i-1] {
+				return fmt.Errorf("%s is in the faction pool more than once", pool[i])
+			}
+		}
+		if available := t.Ruleset.distinctPicks(pool); available < needed {
+			return fmt.Errorf("a series of %d games needs at least %d factions per player but got %d", t.RoundCount, needed, available)
+		}
+	}
+	return nil
+}
+
+/**
+Checks that there are odds for every matchup the players' pools can make, on each map in the pool if there is one, and
+that all the odds are between 0 and 1.
+*/
+func (t TournamentInfo) ValidateOdds() error {
+	for k, v := range t.MatchupOdds {
+		if !(v >= 0 && v <= 1) {
+			matchup, _ := k.MarshalText()
+			return fmt.Errorf("odds for %s should be between 0 and 1 but got %v", matchup, v)
+		}
+	}
+	// Every game is played on a map from the pool when there is one.
+	gameMaps := []GameMap{""}
+	if len(t.Ruleset.MapPool) > 0 {
+		gameMaps = t.Ruleset.MapPool
+	}
+	for _, p1 := range t.PlayerPool(true) {
+		for _, p2 := range t.PlayerPool(false) {
+			for _, gameMap := range gameMaps {
+				if _, ok := LookupMatchupValue(Matchup{P1: p1, P2: p2, Map: gameMap}, t); !ok {
+					return fmt.Errorf("there are no odds for %s v. %s", p1, p2)
+				}
+			}
+		}
+	}
+	return nil
 }
 
 // MatchupsV1d2
 // The matchup values - we only need to express half.
 // Later we will make this a dynamic input but hardcoding for now.
 var MatchupsV1d2 = map[Matchup]float64{
-	Matchup{GC, GC}: .5,
-	Matchup{GC, KH}: .4,
-	Matchup{GC, KI}: .55,
-	Matchup{GC, NG}: .4,
-	Matchup{GC, OK}: .4,
-	Matchup{GC, SL}: .6,
-	Matchup{GC, TZ}: .4,
-
-	Matchup{KH, KH}: .5,
-	Matchup{KH, KI}: .5,
-	Matchup{KH, NG}: .4,
-	Matchup{KH, OK}: .6,
-	Matchup{KH, SL}: .65,
-	Matchup{KH, TZ}: .5,
-
-	Matchup{KI, KI}: .5,
-	Matchup{KI, NG}: .4,
-	Matchup{KI, OK}: .6,
-	Matchup{KI, SL}: .65,
-	Matchup{KI, TZ}: .6,
-
-	Matchup{NG, NG}: .5,
-	Matchup{NG, OK}: .65,
-	Matchup{NG, SL}: .7,
-	Matchup{NG, TZ}: .3,
-
-	Matchup{OK, OK}: .5,
-	Matchup{OK, SL}: .6,
-	Matchup{OK, TZ}: .4,
-
-	Matchup{SL, SL}: .5,
-	Matchup{SL, TZ}: .3,
-
-	Matchup{TZ, TZ}: .5,
+	Matchup{P1: GC, P2: GC}: .5,
+	Matchup{P1: GC, P2: KH}: .4,
+	Matchup{P1: GC, P2: KI}: .55,
+	Matchup{P1: GC, P2: NG}: .4,
+	Matchup{P1: GC, P2: OK}: .4,
+	Matchup{P1: GC, P2: SL}: .6,
+	Matchup{P1: GC, P2: TZ}: .4,
+
+	Matchup{P1: KH, P2: KH}: .5,
+	Matchup{P1: KH, P2: KI}: .5,
+	Matchup{P1: KH, P2: NG}: .4,
+	Matchup{P1: KH, P2: OK}: .6,
+	Matchup{P1: KH, P2: SL}: .65,
+	Matchup{P1: KH, P2: TZ}: .5,
+
+	Matchup{P1: KI, P2: KI}: .5,
+	Matchup{P1: KI, P2: NG}: .4,
+	Matchup{P1: KI, P2: OK}: .6,
+	Matchup{P1: KI, P2: SL}: .65,
+	Matchup{P1: KI, P2: TZ}: .6,
+
+	Matchup{P1: NG, P2: NG}: .5,
+	Matchup{P1: NG, P2: OK}: .65,
+	Matchup{P1: NG, P2: SL}: .7,
+	Matchup{P1: NG, P2: TZ}: .3,
+
+	Matchup{P1: OK, P2: OK}: .5,
+	Matchup{P1: OK, P2: SL}: .6,
+	Matchup{P1: OK, P2: TZ}: .4,
+
+	Matchup{P1: SL, P2: SL}: .5,
+	Matchup{P1: SL, P2: TZ}: .3,
+
+	Matchup{P1: TZ, P2: TZ}: .5,
+}
+
+/**
+A hash of matchup odds that doesn't depend on the order of the map, to tell whether two sets of odds are the same.
+*/
+func OddsHash(matchupOdds map[Matchup]float64) string {
+	var lines []string
+	for k, v := range matchupOdds {
+		key, _ := k.MarshalText()
+		lines = append(lines, fmt.Sprintf("%s=%v", key, v))
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, v := range lines {
+		fmt.Fprintln(hash, v)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
+/**
+Looks up P1's odds in a matchup. The most specific odds available win: odds on the matchup's map before map agnostic
+ones, and within those, odds for the lords being played before odds for their races.
+*/
 func GetMatchupValue(matchup Matchup, tournamentInfo TournamentInfo) float64 {
+	// This is on the hot path of every search, so check the matchup itself before building up the fallbacks.
 	if val, ok := tournamentInfo.MatchupOdds[matchup]; ok {
 		return val
-	} else {
-		// Search for the opposite.
-		oppositeKey := Matchup{P1: matchup.P2, P2: matchup.P1}
-		if val2, ok2 := tournamentInfo.MatchupOdds[oppositeKey]; ok2 {
-			return 1.0 - val2
-		} else {
-			panic(fmt.Sprintf("Could not find matchup results for: %s v. %s ", matchup.P1, matchup.P2))
+	}
+	if val, ok := LookupMatchupValue(matchup, tournamentInfo); ok {
+		return val
+	}
+	panic(fmt.Sprintf("Could not find matchup results for: %s v. %s ", matchup.P1, matchup.P2))
+}
+
+/**
+Like GetMatchupValue, but reports whether there are any odds for the matchup rather than panicking when there aren't.
+*/
+func LookupMatchupValue(matchup Matchup, tournamentInfo TournamentInfo) (float64, bool) {
+	if val, ok := tournamentInfo.MatchupOdds[matchup]; ok {
+		return val, true
+	}
+	if val, ok := tournamentInfo.MatchupOdds[Matchup{P1: matchup.P2, P2: matchup.P1, Map: matchup.Map}]; ok {
+		return 1.0 - val, true
+	}
+
+	gameMaps := []GameMap{matchup.Map}
+	if matchup.Map != "" {
+		gameMaps = append(gameMaps, "")
+	}
+	p1Options := []Faction{matchup.P1}
+	if IsLord(matchup.P1) {
+		p1Options = append(p1Options, Race(matchup.P1))
+	}
+	p2Options := []Faction{matchup.P2}
+	if IsLord(matchup.P2) {
+		p2Options = append(p2Options, Race(matchup.P2))
+	}
+
+	for _, gameMap := range gameMaps {
+		for _, p1 := range p1Options {
+			for _, p2 := range p2Options {
+				if val, ok := tournamentInfo.MatchupOdds[Matchup{P1: p1, P2: p2, Map: gameMap}]; ok {
+					return val, true
+				}
+				// Search for the opposite.
+				if val, ok := tournamentInfo.MatchupOdds[Matchup{P1: p2, P2: p1, Map: gameMap}]; ok {
+					return 1.0 - val, true
+				}
+			}
 		}
 	}
+	return 0.0, false
 }
@@ -0,0 +1,60 @@
+package common
+
+import "sort"
+
+// Legendary lords are written as their race's code and the lord's, e.g. KI:KAT for Katarin.
+const (
+	MiaoYing  Faction = "GC:MY"
+	ZhaoMing  Faction = "GC:ZM"
+	Skarbrand Faction = "KH:SKA"
+	Katarin   Faction = "KI:KAT"
+	Kostaltyn Faction = "KI:KOS"
+	Kugath    Faction = "NG:KUG"
+	Greasus   Faction = "OK:GRE"
+	Skrag     Faction = "OK:SKR"
+	NKari     Faction = "SL:NKA"
+	Kairos    Faction = "TZ:KAI"
+)
+
+// Lords maps every legendary lord to the race they lead. Races themselves are in Factions.
+var Lords = map[Faction]Faction{
+	MiaoYing:  GC,
+	ZhaoMing:  GC,
+	Skarbrand: KH,
+	Katarin:   KI,
+	Kostaltyn: KI,
+	Kugath:    NG,
+	Greasus:   OK,
+	Skrag:     OK,
+	NKari:     SL,
+	Kairos:    TZ,
+}
+
+/**
+The race a faction belongs to - the faction itself if it is already a race.
+*/
+func Race(faction Faction) Faction {
+	if race, ok := Lords[faction]; ok {
+		return race
+	}
+	return faction
+}
+
+func IsLord(faction Faction) bool {
+	_, ok := Lords[faction]
+	return ok
+}
+
+/**
+Every legendary lord, sorted.
+*/
+func AllLords() []Faction {
+	var lords []Faction
+	for k := range Lords {
+		lords = append(lords, k)
+	}
+	sort.Slice(lords, func(i, j int) bool {
+		return lords[i] < lords[j]
+	})
+	return lords
+}
@@ -0,0 +1,232 @@
+package common
+
+import "sort"
+
+// DraftStep is one decision within a game of the draft.
+type DraftStep string
+
+const (
+	// InitialPicks - the first picker of a game offers two factions.
+	InitialPicks DraftStep = "initial-picks"
+	// CounterPick - the other player picks their faction for the game.
+	CounterPick DraftStep = "counter-pick"
+	// FinalPick - the first picker plays one of their two initial picks.
+	FinalPick DraftStep = "final-pick"
+	// LastInitialPicks - in the final game the first picker offers three factions and bans one of the opponent's.
+	LastInitialPicks DraftStep = "last-initial-picks"
+	// LastCounterPick - the other player bans one of the three initial picks and picks their faction.
+	LastCounterPick DraftStep = "last-counter-pick"
+	// LastFinalPick - the first picker plays one of their two remaining initial picks.
+	LastFinalPick DraftStep = "last-final-pick"
+	// MapBan - before the first game, players take turns banning maps from the pool, P1 first.
+	MapBan DraftStep = "map-ban"
+	// MapPick - before each game's faction picks, one player picks the map it is played on.
+	MapPick DraftStep = "map-pick"
+	// GlobalBan - before anything else, players take turns banning a faction their opponent can't play all series,
+	// P1 first.
+	GlobalBan DraftStep = "global-ban"
+	// GameResult - not a decision but the game just drafted being played. It only comes up when the ruleset makes the
+	// rest of the draft depend on who won, and the result hasn't been recorded yet.
+	GameResult DraftStep = "game-result"
+)
+
+// MapPicker says which player picks each game's map.
+type MapPicker string
+
+const (
+	// FirstPickerPicksMap - the player about to make the game's initial picks also picks its map.
+	FirstPickerPicksMap MapPicker = "first-picker"
+	// CounterPickerPicksMap - the map goes to the other player, to make up for picking factions second.
+	CounterPickerPicksMap MapPicker = "counter-picker"
+)
+
+// RepeatGranularity says what a player is barred from playing again once they have played a faction.
+type RepeatGranularity string
+
+const (
+	// RaceRepeats - no race twice, so playing any of a race's lords rules out all of them.
+	RaceRepeats RepeatGranularity = "race"
+	// LordRepeats - no lord twice, but other lords of the same race stay available.
+	LordRepeats RepeatGranularity = "lord"
+	// AllowRepeats - anything can be played again, which is usually paired with WinnerLockedOut.
+	AllowRepeats RepeatGranularity = "allowed"
+)
+
+// FirstPickRule says who makes the initial picks in every game after the first, which P1 always opens.
+type FirstPickRule string
+
+const (
+	// AlternateFirstPick - players take turns picking first, except that the winner of the game before the final one
+	// picks first in it.
+	AlternateFirstPick FirstPickRule = "alternate"
+	// LoserPicksFirst - the loser of the previous game picks first.
+	LoserPicksFirst FirstPickRule = "loser"
+	// WinnerPicksFirst - the winner of the previous game picks first, so the loser gets to counter pick.
+	WinnerPicksFirst FirstPickRule = "winner"
+)
+
+// Ruleset captures the ways a tournament's draft differs from the default Turin rules.
+type Ruleset struct {
+	Name string
+	// SimultaneousSteps marks counter pick steps that are played blind: the counter pick is locked in at the same
+	// time as the first picker's final pick instead of before it. In the final game the counter ban is still
+	// announced first. Only CounterPick and LastCounterPick may be marked.
+	SimultaneousSteps map[DraftStep]bool
+	// MapPool is the maps games can be played on. Maps are only drafted when there is a pool, and no map is played
+	// twice in a series unless the pool runs dry.
+	MapPool []GameMap
+	// MapBansPerPlayer is how many maps each player bans from the pool before the first game.
+	MapBansPerPlayer int
+	// MapPicker defaults to the first picker.
+	MapPicker MapPicker
+	// FactionPool is what can be drafted, races or lords or a mix of both. The races in Factions if empty.
+	FactionPool []Faction
+	// Repeats defaults to RaceRepeats.
+	Repeats RepeatGranularity
+	// FirstPick defaults to AlternateFirstPick.
+	FirstPick FirstPickRule
+	// WinnerLockedOut bars a player from playing a faction again once they have won a game with it, on top of
+	// whatever Repeats rules out.
+	WinnerLockedOut bool
+	// StopWhenDecided drafts each game only once the one before it has been played, and only while the series is
+	// still live. Drafting every game up front gives the same odds when nothing else depends on results, and is much
+	// cheaper to search, but the picks for later games can't then depend on how earlier ones went.
+	StopWhenDecided bool
+	// GlobalBansPerPlayer is how many factions each player bans for the whole series before the draft starts.
+	GlobalBansPerPlayer int
+	// TeamSize is how many factions each side fields per game, one per player. Above 1 the draft is a team draft,
+	// searched with TeamMinimax rather than the Turin search.
+	TeamSize int
+	// TeamOdds defaults to AverageTeamOdds.
+	TeamOdds TeamOddsModel
+}
+
+/**
+What can be drafted under the ruleset, sorted.
+*/
+func (r Ruleset) Pool() []Faction {
+	var pool []Faction
+	if len(r.FactionPool) > 0 {
+		pool = append(pool, r.FactionPool...)
+	} else {
+		for k := range Factions {
+			pool = append(pool, k)
+		}
+	}
+	sort.Slice(pool, func(i, j int) bool {
+		return pool[i] < pool[j]
+	})
+	return pool
+}
+
+/**
+The ruleset with empty lists and maps made nil, so that rulesets that only differ in how they were written down encode
+the same.
+*/
+func (r Ruleset) Canonical() Ruleset {
+	if len(r.MapPool) == 0 {
+		r.MapPool = nil
+	}
+	if len(r.FactionPool) == 0 {
+		r.FactionPool = nil
+	}
+	if len(r.SimultaneousSteps) == 0 {
+		r.SimultaneousSteps = nil
+	}
+	return r
+}
+
+/**
+Whether having played played rules out playing faction later in the series.
+*/
+func (r Ruleset) IsRepeat(played Faction, faction Faction) bool {
+	switch r.Repeats {
+	case AllowRepeats:
+		return false
+	case LordRepeats:
+		return played == faction
+	}
+	return Race(played) == Race(faction)
+}
+
+/**
+Whether who won a game changes what can happen later in the draft, beyond who picks first in the final game.
+*/
+func (r Ruleset) DependsOnResults() bool {
+	return r.FirstPick == LoserPicksFirst || r.FirstPick == WinnerPicksFirst || r.WinnerLockedOut || r.StopWhenDecided
+}
+
+/**
+How many factions in pool can be played without repeating, counting each race once if a race can't be repeated.
+*/
+func (r Ruleset) distinctPicks(pool []Faction) int {
+	if r.Repeats == LordRepeats || r.Repeats == AllowRepeats {
+		return len(pool)
+	}
+	races := map[Faction]bool{}
+	for _, v := range pool {
+		races[Race(v)] = true
+	}
+	return len(races)
+}
+
+func (r Ruleset) IsTeamDraft() bool {
+	return r.TeamSize > 1
+}
+
+func (r Ruleset) DraftsMaps() bool {
+	return len(r.MapPool) > 0
+}
+
+func (r Ruleset) IsSimultaneous(step DraftStep) bool {
+	return r.SimultaneousSteps[step]
+}
+
+var Turin2022Q2 = Ruleset{Name: "2022-Q2-Turin-Default"}
+
+// Turin2022Q2Blind is Turin where counter picks are made blind, as some organizers run it.
+var Turin2022Q2Blind = Ruleset{
+	Name: "2022-Q2-Turin-Blind",
+	SimultaneousSteps: map[DraftStep]bool{
+		CounterPick:     true,
+		LastCounterPick: true,
+	},
+}
+
+// Turin2022Q2Lords is Turin drafting legendary lords instead of races. Each lord can be played once.
+var Turin2022Q2Lords = Ruleset{
+	Name:        "2022-Q2-Turin-Lords",
+	FactionPool: AllLords(),
+	Repeats:     LordRepeats,
+}
+
+// Turin2022Q2GlobalBans is Turin opened by two global bans per player, as in our regional qualifier.
+var Turin2022Q2GlobalBans = Ruleset{
+	Name:                "2022-Q2-Turin-Global-Bans",
+	GlobalBansPerPlayer: 2,
+}
+
+// Turin2022Q2LoserPicks is Turin where the loser of each game picks first in the next one and can play anything
+// again, while the winner can't play the faction they won with.
+var Turin2022Q2LoserPicks = Ruleset{
+	Name:            "2022-Q2-Turin-Loser-Picks",
+	Repeats:         AllowRepeats,
+	FirstPick:       LoserPicksFirst,
+	WinnerLockedOut: true,
+}
+
+// Turin2022Q22v2 is pick two for teams of two: the first picking side offers three factions, the other side counter
+// picks two and the first side plays two of its three. Team drafts aren't in the web UI yet, so it isn't in Rulesets.
+var Turin2022Q22v2 = Ruleset{
+	Name:     "2022-Q2-Turin-2v2",
+	TeamSize: 2,
+}
+
+// Rulesets are the rulesets that can be picked by name, e.g. from the web UI.
+var Rulesets = map[string]Ruleset{
+	Turin2022Q2.Name:           Turin2022Q2,
+	Turin2022Q2Blind.Name:      Turin2022Q2Blind,
+	Turin2022Q2Lords.Name:      Turin2022Q2Lords,
+	Turin2022Q2GlobalBans.Name: Turin2022Q2GlobalBans,
+	Turin2022Q2LoserPicks.Name: Turin2022Q2LoserPicks,
+}
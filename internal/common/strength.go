@@ -0,0 +1,19 @@
+package common
+
+import "math"
+
+// EloScale is how many strength points make one player ten times as likely to win as another.
+const EloScale = 400.0
+
+/**
+Shifts a matchup's odds to account for the players, where strengthDiff is how much stronger P1 is than P2 on their
+factions, in Elo points. The shift happens on the log odds scale so it never pushes odds past 0 or 1, and matchups
+that are already certain stay that way.
+*/
+func AdjustForStrength(odds float64, strengthDiff float64) float64 {
+	if odds <= 0.0 || odds >= 1.0 {
+		return odds
+	}
+	logOdds := math.Log(odds/(1.0-odds)) + strengthDiff*math.Ln10/EloScale
+	return 1.0 / (1.0 + math.Exp(-logOdds))
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"fmt"
+	"math"
+)
+
+// TeamOddsModel says how a team game's odds are built from the odds of every pairing of factions across the two sides.
+type TeamOddsModel string
+
+const (
+	// AverageTeamOdds - the mean of the pairwise odds.
+	AverageTeamOdds TeamOddsModel = "average"
+	// LogitTeamOdds - the mean of the pairwise odds on the log odds scale, so one lopsided pairing counts for more
+	// than it would in the plain average.
+	LogitTeamOdds TeamOddsModel = "logit"
+)
+
+/**
+TeamMatchup is a team game: the factions each side fields, one per player, and the map it is played on.
+*/
+type TeamMatchup struct {
+	P1  []Faction
+	P2  []Faction
+	Map GameMap
+}
+
+/**
+Looks up P1's odds in a team game by combining the odds of every pairing of a P1 faction against a P2 faction, found
+the usual way with GetMatchupValue, through the ruleset's TeamOdds model.
+*/
+func GetTeamMatchupValue(matchup TeamMatchup, tournamentInfo TournamentInfo) float64 {
+	var pairwise []float64
+	for _, p1 := range matchup.P1 {
+		for _, p2 := range matchup.P2 {
+			pairwise = append(pairwise, GetMatchupValue(Matchup{P1: p1, P2: p2, Map: matchup.Map}, tournamentInfo))
+		}
+	}
+	if len(pairwise) == 0 {
+		panic(fmt.Sprintf("Could not find team matchup results for: %v v. %v ", matchup.P1, matchup.P2))
+	}
+	return tournamentInfo.Ruleset.TeamOdds.combine(pairwise)
+}
+
+func (m TeamOddsModel) combine(pairwise []float64) float64 {
+	switch m {
+	case LogitTeamOdds:
+		total := 0.0
+		for _, v := range pairwise {
+			// Clamp so that a certain pairing shifts the result a long way without making it certain too.
+			odds := math.Min(math.Max(v, 1e-6), 1-1e-6)
+			total += math.Log(odds / (1.0 - odds))
+		}
+		return 1.0 / (1.0 + math.Exp(-total/float64(len(pairwise))))
+	default:
+		total := 0.0
+		for _, v := range pairwise {
+			total += v
+		}
+		return total / float64(len(pairwise))
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+Config is how the web server is set up. Each setting comes from the defaults, then a JSON config file, then
+environment variables, then command line flags, with each overriding the one before.
+*/
+type Config struct {
+	// Addr is the address to listen on, e.g. :8080 or 127.0.0.1:8080.
+	Addr string `json:"addr"`
+	// TemplateDir holds HTML templates to use in place of the built in ones. Relative paths are from the working
+	// directory.
+	TemplateDir string `json:"templateDir"`
+	// StaticDir is served under /static in place of the built in static files.
+	StaticDir string `json:"staticDir"`
+	EventDir  string `json:"eventDir"`
+	// Ruleset is the name of the ruleset drafts and events use unless they ask for another.
+	Ruleset string `json:"ruleset"`
+	// MatchupOddsFile is a JSON object of P1-P2 matchups to P1's odds. Matchups it leaves out use MatchupsV1d2.
+	MatchupOddsFile string `json:"matchupOddsFile"`
+	// SearchBudget caps how long a recommendation for the series win rate or a draft review may search for, with zero
+	// searching to the end of the draft. Other objectives are always searched to the end.
+	SearchBudget Duration `json:"searchBudget"`
+	// MaxRoundCount is the longest series the server will draft.
+	MaxRoundCount int `json:"maxRoundCount"`
+	// CacheSize is how many search results are kept in memory, with zero keeping none.
+	CacheSize int `json:"cacheSize"`
+	// CacheDir also keeps every search result on disk if set, so they survive restarts.
+	CacheDir string `json:"cacheDir"`
+	// BookDir holds opening books built by wh3-openingbook, which recommendations are taken from where they match.
+	BookDir string `json:"bookDir"`
+	// Mode is gin's mode: debug, release or test.
+	Mode string `json:"mode"`
+	// AccessLog logs every request.
+	AccessLog bool `json:"accessLog"`
+	// LogFormat is json for log collectors or text for reading by eye.
+	LogFormat string `json:"logFormat"`
+}
+
+// Duration is a time.Duration written like 1.5s or 2m in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		Addr:          ":8080",
+		EventDir:      "data/events",
+		Ruleset:       Turin2022Q2.Name,
+		MaxRoundCount: MaxRoundCount,
+		CacheSize:     1000,
+		Mode:          "debug",
+		AccessLog:     true,
+		LogFormat:     "text",
+	}
+}
+
+/**
+Builds the config from the file named by the -config flag or the CONFIG environment variable, the environment and the
+rest of args, which are the command line arguments without the program name.
+*/
+func Load(args []string) (Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	config := Default()
+
+	// Flags are parsed up front to find the config file, but only applied last.
+	flags := flag.NewFlagSet("wh3-draftbot", flag.ContinueOnError)
+	var flagConfig Config
+	configFile := flags.String("config", "", "JSON config file")
+	flags.StringVar(&flagConfig.Addr, "addr", "", "address to listen on")
+	flags.StringVar(&flagConfig.TemplateDir, "template-dir", "", "directory of HTML templates to use in place of the built in ones")
+	flags.StringVar(&flagConfig.StaticDir, "static-dir", "", "directory to serve under /static in place of the built in one")
+	flags.StringVar(&flagConfig.EventDir, "event-dir", "", "directory events are saved in")
+	flags.StringVar(&flagConfig.Ruleset, "ruleset", "", "default ruleset")
+	flags.StringVar(&flagConfig.MatchupOddsFile, "matchup-odds", "", "JSON file of default matchup odds")
+	flags.DurationVar(&flagConfig.SearchBudget.Duration, "search-budget", 0, "longest a recommendation or review may search for, 0 for no limit")
+	flags.IntVar(&flagConfig.MaxRoundCount, "max-rounds", 0, "longest series to draft")
+	flags.IntVar(&flagConfig.CacheSize, "cache-size", 0, "search results to keep in memory")
+	flags.StringVar(&flagConfig.CacheDir, "cache-dir", "", "directory to keep search results in")
+	flags.StringVar(&flagConfig.BookDir, "book-dir", "", "directory of opening books")
+	flags.StringVar(&flagConfig.Mode, "mode", "", "gin mode: debug, release or test")
+	flags.BoolVar(&flagConfig.AccessLog, "access-log", false, "log every request")
+	flags.StringVar(&flagConfig.LogFormat, "log-format", "", "log format: json or text")
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv("CONFIG")
+	}
+	if *configFile != "" {
+		bytes, err := os.ReadFile(*configFile)
+		if err != nil {
+			return config, err
+		}
+		if err := json.Unmarshal(bytes, &config); err != nil {
+			return config, fmt.Errorf("could not read config file %s: %w", *configFile, err)
+		}
+	}
+
+	if err := applyEnv(&config, lookupEnv); err != nil {
+		return config, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			config.Addr = flagConfig.Addr
+		case "template-dir":
+			config.TemplateDir = flagConfig.TemplateDir
+		case "static-dir":
+			config.StaticDir = flagConfig.StaticDir
+		case "event-dir":
+			config.EventDir = flagConfig.EventDir
+		case "ruleset":
+			config.Ruleset = flagConfig.Ruleset
+		case "matchup-odds":
+			config.MatchupOddsFile = flagConfig.MatchupOddsFile
+		case "search-budget":
+			config.SearchBudget = flagConfig.SearchBudget
+		case "max-rounds":
+			config.MaxRoundCount = flagConfig.MaxRoundCount
+		case "cache-size":
+			config.CacheSize = flagConfig.CacheSize
+		case "cache-dir":
+			config.CacheDir = flagConfig.CacheDir
+		case "book-dir":
+			config.BookDir = flagConfig.BookDir
+		case "mode":
+			config.Mode = flagConfig.Mode
+		case "access-log":
+			config.AccessLog = flagConfig.AccessLog
+		case "log-format":
+			config.LogFormat = flagConfig.LogFormat
+		}
+	})
+	return config, config.Validate()
+}
+
+/**
+Environment variables are named after the flags, upper cased with underscores, e.g. EVENT_DIR. PORT is kept for hosts
+that set it and listens on every interface, but ADDR wins if both are set.
+*/
+func applyEnv(config *Config, lookupEnv func(string) (string, bool)) error {
+	if port, ok := lookupEnv("PORT"); ok {
+		config.Addr = ":" + port
+	}
+	stringSettings := map[string]*string{
+		"ADDR":         &config.Addr,
+		"TEMPLATE_DIR": &config.TemplateDir,
+		"STATIC_DIR":   &config.StaticDir,
+		"EVENT_DIR":    &config.EventDir,
+		"RULESET":      &config.Ruleset,
+		"MATCHUP_ODDS": &config.MatchupOddsFile,
+		"CACHE_DIR":    &config.CacheDir,
+		"BOOK_DIR":     &config.BookDir,
+		"GIN_MODE":     &config.Mode,
+		"LOG_FORMAT":   &config.LogFormat,
+	}
+	for name, setting := range stringSettings {
+		if value, ok := lookupEnv(name); ok && value != "" {
+			*setting = value
+		}
+	}
+
+	if value, ok := lookupEnv("SEARCH_BUDGET"); ok && value != "" {
+		if err := config.SearchBudget.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("could not read SEARCH_BUDGET: %w", err)
+		}
+	}
+	if value, ok := lookupEnv("MAX_ROUNDS"); ok && value != "" {
+		maxRoundCount, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("could not read MAX_ROUNDS: %w", err)
+		}
+		config.MaxRoundCount = maxRoundCount
+	}
+	if value, ok := lookupEnv("CACHE_SIZE"); ok && value != "" {
+		cacheSize, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("could not read CACHE_SIZE: %w", err)
+		}
+		config.CacheSize = cacheSize
+	}
+	if value, ok := lookupEnv("ACCESS_LOG"); ok && value != "" {
+		accessLog, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("could not read ACCESS_LOG: %w", err)
+		}
+		config.AccessLog = accessLog
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	if _, ok := Rulesets[c.Ruleset]; !ok {
+		return fmt.Errorf("unknown ruleset: %s", c.Ruleset)
+	}
+	if err := ValidateRoundCount(c.MaxRoundCount); err != nil {
+		return fmt.Errorf("max round count: %w", err)
+	}
+	if c.CacheSize < 0 {
+		return fmt.Errorf("cache size can't be negative but got %d", c.CacheSize)
+	}
+	if c.SearchBudget.Duration < 0 {
+		return fmt.Errorf("search budget can't be negative but got %s", c.SearchBudget)
+	}
+	switch c.Mode {
+	case "debug", "release", "test":
+	default:
+		return fmt.Errorf("mode should be debug, release or test but got: %s", c.Mode)
+	}
+	switch c.LogFormat {
+	case "json", "text":
+	default:
+		return fmt.Errorf("log format should be json or text but got: %s", c.LogFormat)
+	}
+	return nil
+}
+
+/**
+The matchup odds drafts and events start from: MatchupsV1d2, overridden by MatchupOddsFile if set.
+*/
+func (c Config) MatchupOdds() (map[Matchup]float64, error) {
+	odds := map[Matchup]float64{}
+	for k, v := range MatchupsV1d2 {
+		odds[k] = v
+	}
+	if c.MatchupOddsFile == "" {
+		return odds, nil
+	}
+	bytes, err := os.ReadFile(c.MatchupOddsFile)
+	if err != nil {
+		return nil, err
+	}
+	var fileOdds map[Matchup]float64
+	if err := json.Unmarshal(bytes, &fileOdds); err != nil {
+		return nil, fmt.Errorf("could not read matchup odds %s: %w", c.MatchupOddsFile, err)
+	}
+	for k, v := range fileOdds {
+		if v < 0 || v > 1 {
+			return nil, fmt.Errorf("odds for %s-%s should be between 0 and 1 but got %f", k.P1, k.P2, v)
+		}
+		odds[k] = v
+	}
+	return odds, nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Expected to write %s but got %s", name, err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	config, err := load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Expected the defaults to be valid but got %s", err)
+	}
+	if config != Default() {
+		t.Errorf("Expected the defaults but got %+v", config)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.json", `{"addr": "127.0.0.1:9000", "eventDir": "file-events", "ruleset": "2022-Q2-Turin-Lords", "searchBudget": "2s", "maxRoundCount": 7}`)
+
+	// Env beats the file and flags beat env.
+	config, err := load([]string{"-config", file, "-max-rounds", "5"}, env(map[string]string{"EVENT_DIR": "env-events", "MAX_ROUNDS": "3"}))
+	if err != nil {
+		t.Fatalf("Expected the config to load but got %s", err)
+	}
+	expected := Default()
+	expected.Addr = "127.0.0.1:9000"
+	expected.EventDir = "env-events"
+	expected.Ruleset = Turin2022Q2Lords.Name
+	expected.SearchBudget = Duration{2 * time.Second}
+	expected.MaxRoundCount = 5
+	if config != expected {
+		t.Errorf("Expected %+v but got %+v", expected, config)
+	}
+
+	// The file can come from the environment too.
+	config, _ = load(nil, env(map[string]string{"CONFIG": file, "PORT": "8081"}))
+	if config.Addr != ":8081" || config.EventDir != "file-events" {
+		t.Errorf("Expected PORT over the file's address and the file's event dir but got %+v", config)
+	}
+	config, _ = load(nil, env(map[string]string{"PORT": "8081", "ADDR": "localhost:8082"}))
+	if config.Addr != "localhost:8082" {
+		t.Errorf("Expected ADDR to beat PORT but got %s", config.Addr)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	for name, args := range map[string][]string{
+		"an unknown ruleset":    {"-ruleset", "2022-Q2-Turin-Nope"},
+		"an even round count":   {"-max-rounds", "4"},
+		"an unknown mode":       {"-mode", "production"},
+		"an unknown log format": {"-log-format", "xml"},
+		"an unknown flag":       {"-verbose"},
+	} {
+		if _, err := load(args, env(nil)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+	if _, err := load(nil, env(map[string]string{"SEARCH_BUDGET": "soon"})); err == nil {
+		t.Errorf("Expected an unreadable search budget to be rejected")
+	}
+	if _, err := load([]string{"-config", filepath.Join(t.TempDir(), "missing.json")}, env(nil)); err == nil {
+		t.Errorf("Expected a missing config file to be rejected")
+	}
+}
+
+func TestMatchupOdds(t *testing.T) {
+	config := Default()
+	config.MatchupOddsFile = writeFile(t, "odds.json", `{"GC-KH": 0.7, "KI:KAT-NG@Black Fortress": 0.4}`)
+	odds, err := config.MatchupOdds()
+	if err != nil {
+		t.Fatalf("Expected the odds to load but got %s", err)
+	}
+	if odds[Matchup{P1: GC, P2: KH}] != .7 || odds[Matchup{P1: Katarin, P2: NG, Map: "Black Fortress"}] != .4 {
+		t.Errorf("Expected the file's odds but got %v", odds)
+	}
+	if odds[Matchup{P1: GC, P2: KI}] != MatchupsV1d2[Matchup{P1: GC, P2: KI}] {
+		t.Errorf("Expected matchups the file leaves out to keep their usual odds")
+	}
+
+	config.MatchupOddsFile = writeFile(t, "odds.json", `{"GC-KH": 70}`)
+	if _, err := config.MatchupOdds(); err == nil {
+		t.Errorf("Expected odds over one to be rejected")
+	}
+}
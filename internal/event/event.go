@@ -0,0 +1,338 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	"github.com/tmwilder/wh3-draftbot/internal/bracket"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"sort"
+)
+
+type Format string
+
+const (
+	Swiss      Format = "swiss"
+	RoundRobin Format = "round-robin"
+)
+
+/**
+Event is a tournament as the organizer runs it: who is playing, who plays whom each round and how those matches went.
+*/
+type Event struct {
+	ID     string
+	Name   string
+	Format Format
+	// Players in seed order, top seed first.
+	Players []string
+	// SwissRounds is how many rounds a Swiss event runs for. Round robin events play everyone once.
+	SwissRounds int
+	// TournamentInfo is the draft every match is played under.
+	TournamentInfo TournamentInfo
+	// Rounds paired so far. Round robin events are paired in full up front, Swiss ones a round at a time.
+	Rounds []Round
+}
+
+type Round struct {
+	Pairings []Pairing
+}
+
+/**
+Pairing is one match of a round. The better seed is P1 in the draft. A pairing with no P2 is a bye, which counts as a
+win for P1.
+*/
+type Pairing struct {
+	P1        string
+	P2        string
+	GameState algo.GameState
+	Winner    WhoWon
+}
+
+func (p Pairing) IsBye() bool {
+	return p.P2 == ""
+}
+
+func (p Pairing) IsComplete() bool {
+	return p.IsBye() || p.Winner != NoOneYet
+}
+
+// Standing is where a player sits in an event, along with the tiebreakers that put them there.
+type Standing struct {
+	Player string
+	Wins   int
+	Losses int
+	Byes   int
+	// Points is wins plus byes.
+	Points int
+	// Buchholz sums the points of every opponent played, SonnebornBerger those of every opponent beaten.
+	Buchholz        int
+	SonnebornBerger int
+}
+
+/**
+Creates an event and pairs its opening round - or every round, for round robin.
+*/
+func NewEvent(name string, format Format, players []string, swissRounds int, tournamentInfo TournamentInfo) (Event, error) {
+	if name == "" {
+		return Event{}, errors.New("events need a name")
+	}
+	if len(players) < 2 {
+		return Event{}, fmt.Errorf("need at least 2 players but got %d", len(players))
+	}
+	seen := map[string]bool{}
+	for _, v := range players {
+		if v == "" || seen[v] {
+			return Event{}, fmt.Errorf("player names must be unique and not blank, got: %q", v)
+		}
+		seen[v] = true
+	}
+	if err := tournamentInfo.Validate(); err != nil {
+		return Event{}, err
+	}
+	// Every draft of the event is searched with these odds, so they have to cover its pools.
+	if err := tournamentInfo.ValidateOdds(); err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Name:           name,
+		Format:         format,
+		Players:        players,
+		TournamentInfo: tournamentInfo,
+	}
+	switch format {
+	case Swiss:
+		if swissRounds < 1 || swissRounds >= len(players)+len(players)%2 {
+			return Event{}, fmt.Errorf("swiss with %d players needs between 1 and %d rounds but got %d", len(players), len(players)+len(players)%2-1, swissRounds)
+		}
+		event.SwissRounds = swissRounds
+		event.Rounds = []Round{event.pairSwissRound()}
+	case RoundRobin:
+		event.Rounds = event.roundRobinSchedule()
+	default:
+		return Event{}, fmt.Errorf("unknown format: %s", format)
+	}
+	return event, nil
+}
+
+/**
+Records the draft and result of a match. Leave winner as NoOneYet to save a draft that is still being played. The draft
+has to be legal under the event's rules, and once its results decide the series the winner has to agree with them.
+*/
+func (e *Event) RecordResult(round int, pairing int, gameState algo.GameState, winner WhoWon) error {
+	if round < 0 || round >= len(e.Rounds) || pairing < 0 || pairing >= len(e.Rounds[round].Pairings) {
+		return fmt.Errorf("no pairing %d in round %d", pairing, round)
+	}
+	if winner != P1 && winner != P2 && winner != NoOneYet {
+		return fmt.Errorf("winner must be P1, P2 or blank but got: %s", winner)
+	}
+	p := &e.Rounds[round].Pairings[pairing]
+	if p.IsBye() {
+		return errors.New("byes have no match to record")
+	}
+	for i, v := range gameState.P2Rounds {
+		if v.WhoWon != P1 && v.WhoWon != P2 && v.WhoWon != NoOneYet {
+			return fmt.Errorf("game %d's result must be P1, P2 or blank but got: %s", i+1, v.WhoWon)
+		}
+	}
+	if err := algo.ValidateGameState(e.TournamentInfo, gameState); err != nil {
+		return err
+	}
+	if decided := algo.SeriesWinner(e.TournamentInfo, gameState); decided != NoOneYet && winner != NoOneYet && winner != decided {
+		return fmt.Errorf("the draft's results give the series to %s but the winner was %s", decided, winner)
+	}
+	p.GameState = gameState
+	p.Winner = winner
+	return nil
+}
+
+func (e Event) IsRoundComplete(round int) bool {
+	for _, v := range e.Rounds[round].Pairings {
+		if !v.IsComplete() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e Event) IsComplete() bool {
+	if e.Format == Swiss && len(e.Rounds) < e.SwissRounds {
+		return false
+	}
+	for i := range e.Rounds {
+		if !e.IsRoundComplete(i) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+Pairs the next round of a Swiss event from the current standings, once every match of the latest round is in.
+*/
+func (e *Event) NextRound() error {
+	if e.Format != Swiss {
+		return errors.New("only swiss events are paired round by round")
+	}
+	if len(e.Rounds) >= e.SwissRounds {
+		return fmt.Errorf("all %d rounds have already been paired", e.SwissRounds)
+	}
+	if !e.IsRoundComplete(len(e.Rounds) - 1) {
+		return errors.New("the current round still has matches to play")
+	}
+	e.Rounds = append(e.Rounds, e.pairSwissRound())
+	return nil
+}
+
+/**
+Ranks players by points, then Buchholz, then Sonneborn-Berger, then seed. Only finished matches count.
+*/
+func (e Event) Standings() []Standing {
+	seeds := e.seeds()
+	standings := make([]Standing, len(e.Players))
+	for i, v := range e.Players {
+		standings[i].Player = v
+	}
+
+	var opponents, beaten [][]int
+	opponents = make([][]int, len(e.Players))
+	beaten = make([][]int, len(e.Players))
+	for _, round := range e.Rounds {
+		for _, p := range round.Pairings {
+			p1 := seeds[p.P1]
+			if p.IsBye() {
+				standings[p1].Byes++
+				continue
+			}
+			p2 := seeds[p.P2]
+			opponents[p1] = append(opponents[p1], p2)
+			opponents[p2] = append(opponents[p2], p1)
+			switch p.Winner {
+			case P1:
+				standings[p1].Wins++
+				standings[p2].Losses++
+				beaten[p1] = append(beaten[p1], p2)
+			case P2:
+				standings[p2].Wins++
+				standings[p1].Losses++
+				beaten[p2] = append(beaten[p2], p1)
+			}
+		}
+	}
+	for i := range standings {
+		standings[i].Points = standings[i].Wins + standings[i].Byes
+	}
+	for i := range standings {
+		for _, v := range opponents[i] {
+			standings[i].Buchholz += standings[v].Points
+		}
+		for _, v := range beaten[i] {
+			standings[i].SonnebornBerger += standings[v].Points
+		}
+	}
+
+	// Standings start out in seed order so a stable sort falls back on seed.
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		return a.SonnebornBerger > b.SonnebornBerger
+	})
+	return standings
+}
+
+func (e Event) seeds() map[string]int {
+	seeds := map[string]int{}
+	for i, v := range e.Players {
+		seeds[v] = i
+	}
+	return seeds
+}
+
+/**
+Pairs players by standings, avoiding rematches where possible. See bracket.PairSwiss.
+*/
+func (e Event) pairSwissRound() Round {
+	seeds := e.seeds()
+	var order []int
+	for _, v := range e.Standings() {
+		order = append(order, seeds[v.Player])
+	}
+	played := map[[2]int]bool{}
+	hadBye := map[int]bool{}
+	for _, round := range e.Rounds {
+		for _, p := range round.Pairings {
+			if p.IsBye() {
+				hadBye[seeds[p.P1]] = true
+				continue
+			}
+			played[[2]int{seeds[p.P1], seeds[p.P2]}] = true
+			played[[2]int{seeds[p.P2], seeds[p.P1]}] = true
+		}
+	}
+
+	pairings, bye := bracket.PairSwiss(order, played, hadBye)
+	var round Round
+	for _, v := range pairings {
+		round.Pairings = append(round.Pairings, e.newPairing(v[0], v[1]))
+	}
+	if bye != -1 {
+		round.Pairings = append(round.Pairings, Pairing{P1: e.Players[bye]})
+	}
+	return round
+}
+
+/**
+The circle method: seed 1 stays put while everyone else rotates one place each round, so that every pair meets exactly
+once. With an odd number of players whoever would face the empty seat has a bye.
+*/
+func (e Event) roundRobinSchedule() []Round {
+	circle := make([]int, len(e.Players))
+	for i := range circle {
+		circle[i] = i
+	}
+	if len(circle)%2 == 1 {
+		circle = append(circle, -1)
+	}
+
+	var rounds []Round
+	for r := 0; r < len(circle)-1; r++ {
+		var round Round
+		var bye *Pairing
+		for i := 0; i < len(circle)/2; i++ {
+			a, b := circle[i], circle[len(circle)-1-i]
+			switch {
+			case a == -1:
+				bye = &Pairing{P1: e.Players[b]}
+			case b == -1:
+				bye = &Pairing{P1: e.Players[a]}
+			default:
+				round.Pairings = append(round.Pairings, e.newPairing(a, b))
+			}
+		}
+		if bye != nil {
+			round.Pairings = append(round.Pairings, *bye)
+		}
+		rounds = append(rounds, round)
+
+		last := circle[len(circle)-1]
+		copy(circle[2:], circle[1:len(circle)-1])
+		circle[1] = last
+	}
+	return rounds
+}
+
+func (e Event) newPairing(a int, b int) Pairing {
+	if b < a {
+		a, b = b, a
+	}
+	return Pairing{
+		P1:        e.Players[a],
+		P2:        e.Players[b],
+		GameState: algo.GameState{P2Rounds: []algo.P2Round{}, P3Round: algo.P3Round{}},
+	}
+}
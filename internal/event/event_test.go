@@ -0,0 +1,211 @@
+package event
+
+import (
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"reflect"
+	"testing"
+)
+
+var tournamentInfo = TournamentInfo{RoundCount: 3, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2}
+
+func TestRoundRobinEveryoneMeetsOnce(t *testing.T) {
+	for _, players := range [][]string{{"A", "B", "C", "D"}, {"A", "B", "C", "D", "E"}} {
+		event, err := NewEvent("RR", RoundRobin, players, 0, tournamentInfo)
+		if err != nil {
+			t.Fatalf("Expected no error but got %s", err)
+		}
+		met := map[[2]string]int{}
+		byes := map[string]int{}
+		for _, round := range event.Rounds {
+			playing := map[string]bool{}
+			for _, p := range round.Pairings {
+				if playing[p.P1] || playing[p.P2] {
+					t.Errorf("Expected nobody to play twice in a round: %+v", round)
+				}
+				playing[p.P1] = true
+				if p.IsBye() {
+					byes[p.P1]++
+					continue
+				}
+				playing[p.P2] = true
+				met[[2]string{p.P1, p.P2}]++
+			}
+		}
+		expectedMatches := len(players) * (len(players) - 1) / 2
+		if len(met) != expectedMatches {
+			t.Errorf("Expected %d distinct matches for %d players but got %d", expectedMatches, len(players), len(met))
+		}
+		for k, v := range met {
+			if v != 1 {
+				t.Errorf("Expected %v to meet once but they met %d times", k, v)
+			}
+		}
+		if len(players)%2 == 1 && len(byes) != len(players) {
+			t.Errorf("Expected every player to get one bye but got %v", byes)
+		}
+	}
+}
+
+func TestSwissPairsByStandings(t *testing.T) {
+	event, err := NewEvent("Swiss", Swiss, []string{"A", "B", "C", "D"}, 3, tournamentInfo)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	if err := event.NextRound(); err == nil {
+		t.Errorf("Expected pairing before results are in to fail")
+	}
+	// A beats B, D upsets C.
+	_ = event.RecordResult(0, 0, algo.GameState{}, P1)
+	_ = event.RecordResult(0, 1, algo.GameState{}, P2)
+	if err := event.NextRound(); err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+
+	expected := []Pairing{
+		{P1: "A", P2: "D", GameState: algo.GameState{P2Rounds: []algo.P2Round{}}},
+		{P1: "B", P2: "C", GameState: algo.GameState{P2Rounds: []algo.P2Round{}}},
+	}
+	if !reflect.DeepEqual(event.Rounds[1].Pairings, expected) {
+		t.Errorf("Expected winners and losers to meet with the better seed as P1: %+v", event.Rounds[1].Pairings)
+	}
+}
+
+func TestSwissBye(t *testing.T) {
+	event, _ := NewEvent("Swiss", Swiss, []string{"A", "B", "C"}, 3, tournamentInfo)
+	for r := 0; r < 3; r++ {
+		for i, p := range event.Rounds[r].Pairings {
+			if !p.IsBye() {
+				_ = event.RecordResult(r, i, algo.GameState{}, P1)
+			}
+		}
+		if r < 2 {
+			if err := event.NextRound(); err != nil {
+				t.Fatalf("Expected no error but got %s", err)
+			}
+		}
+	}
+	if err := event.NextRound(); err == nil {
+		t.Errorf("Expected pairing past the last round to fail")
+	}
+	for _, v := range event.Standings() {
+		if v.Byes != 1 {
+			t.Errorf("Expected everyone to get exactly one bye but %s got %d", v.Player, v.Byes)
+		}
+	}
+	if !event.IsComplete() {
+		t.Errorf("Expected the event to be complete")
+	}
+}
+
+func TestStandingsTiebreakers(t *testing.T) {
+	event := Event{
+		Format:  RoundRobin,
+		Players: []string{"A", "B", "C", "D"},
+		Rounds: []Round{
+			{Pairings: []Pairing{{P1: "A", P2: "B", Winner: P2}, {P1: "C", P2: "D", Winner: P1}}},
+			{Pairings: []Pairing{{P1: "A", P2: "C", Winner: P1}, {P1: "B", P2: "D", Winner: P2}}},
+		},
+	}
+	// Everyone is on 1 point with the same Buchholz and Sonneborn-Berger, so seed decides.
+	var order []string
+	for _, v := range event.Standings() {
+		order = append(order, v.Player)
+	}
+	if !reflect.DeepEqual(order, []string{"A", "B", "C", "D"}) {
+		t.Errorf("Expected a full tie to fall back on seed but got %v", order)
+	}
+
+	event.Rounds = append(event.Rounds, Round{Pairings: []Pairing{{P1: "A", P2: "D", Winner: P1}, {P1: "B", P2: "C", Winner: P1}}})
+	standings := event.Standings()
+	order = nil
+	for _, v := range standings {
+		order = append(order, v.Player)
+	}
+	// A and B are on 2 points with Buchholz 4, but B beat A and C (2 + 1) while A only beat C and D (1 + 1). C and D
+	// are on 1 point with Buchholz 5, and D's win over B is worth more than C's over D.
+	if !reflect.DeepEqual(order, []string{"B", "A", "D", "C"}) {
+		t.Errorf("Expected Sonneborn-Berger to break the ties but got %v", order)
+	}
+	if standings[0].Buchholz != 4 || standings[0].SonnebornBerger != 3 {
+		t.Errorf("Expected B to have Buchholz 4 and Sonneborn-Berger 3 but got %+v", standings[0])
+	}
+}
+
+func TestRecordResultErrors(t *testing.T) {
+	event, _ := NewEvent("Swiss", Swiss, []string{"A", "B", "C"}, 2, tournamentInfo)
+	if err := event.RecordResult(1, 0, algo.GameState{}, P1); err == nil {
+		t.Errorf("Expected an error for a round that doesn't exist")
+	}
+	if err := event.RecordResult(0, 1, algo.GameState{}, P1); err == nil {
+		t.Errorf("Expected an error for recording a bye")
+	}
+	if err := event.RecordResult(0, 0, algo.GameState{}, "P3"); err == nil {
+		t.Errorf("Expected an error for an unknown winner")
+	}
+
+	// P1 can only play one of their own initial picks.
+	illegal := algo.GameState{P2Rounds: []algo.P2Round{{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: NG, P2: GC}}}}
+	if err := event.RecordResult(0, 0, illegal, NoOneYet); err == nil {
+		t.Errorf("Expected an error for a draft that breaks the event's rules")
+	}
+	// P2 won both of the first two games, so P1 can't have won the series.
+	sweep := algo.GameState{P2Rounds: []algo.P2Round{
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}, WhoWon: P2},
+		{Picks: []Faction{KH, OK}, Matchup: Matchup{P1: NG, P2: KH}, WhoWon: P2},
+	}}
+	if err := event.RecordResult(0, 0, sweep, P1); err == nil {
+		t.Errorf("Expected an error for a winner the draft's results contradict")
+	}
+	if err := event.RecordResult(0, 0, sweep, P2); err != nil {
+		t.Errorf("Expected the sweep to be recorded for P2 but got %s", err)
+	}
+}
+
+func TestNewEventValidation(t *testing.T) {
+	cases := []struct {
+		name        string
+		format      Format
+		players     []string
+		swissRounds int
+	}{
+		{"", RoundRobin, []string{"A", "B"}, 0},
+		{"E", RoundRobin, []string{"A"}, 0},
+		{"E", RoundRobin, []string{"A", "A"}, 0},
+		{"E", Swiss, []string{"A", "B", "C", "D"}, 4},
+		{"E", Swiss, []string{"A", "B"}, 0},
+		{"E", "knockout", []string{"A", "B"}, 0},
+	}
+	for _, v := range cases {
+		if _, err := NewEvent(v.name, v.format, v.players, v.swissRounds, tournamentInfo); err == nil {
+			t.Errorf("Expected an error for %+v", v)
+		}
+	}
+
+	// Every draft of the event is searched with its odds.
+	badOdds := TournamentInfo{RoundCount: 3, MatchupOdds: map[Matchup]float64{{P1: GC, P2: KH}: 1.5}, Ruleset: Turin2022Q2}
+	for k, v := range MatchupsV1d2 {
+		if k != (Matchup{P1: GC, P2: KH}) {
+			badOdds.MatchupOdds[k] = v
+		}
+	}
+	if _, err := NewEvent("E", RoundRobin, []string{"A", "B"}, 0, badOdds); err == nil {
+		t.Errorf("Expected an error for odds over 1")
+	}
+	missingOdds := TournamentInfo{RoundCount: 3, MatchupOdds: map[Matchup]float64{{P1: GC, P2: KH}: .5}, Ruleset: Turin2022Q2}
+	if _, err := NewEvent("E", RoundRobin, []string{"A", "B"}, 0, missingOdds); err == nil {
+		t.Errorf("Expected an error for a pool without odds")
+	}
+
+	// Series have to be an odd length, and a Bo7 runs out of races to play without repeats.
+	for _, roundCount := range []int{0, 2, 7, 11} {
+		info := TournamentInfo{RoundCount: roundCount, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2}
+		if _, err := NewEvent("E", RoundRobin, []string{"A", "B"}, 0, info); err == nil {
+			t.Errorf("Expected an error for a series of %d games", roundCount)
+		}
+	}
+	info := TournamentInfo{RoundCount: 7, MatchupOdds: MatchupsV1d2, Ruleset: Turin2022Q2Lords}
+	if _, err := NewEvent("E", RoundRobin, []string{"A", "B"}, 0, info); err != nil {
+		t.Errorf("Expected a Bo7 of lords to be fine but got: %s", err)
+	}
+}
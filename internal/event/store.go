@@ -0,0 +1,154 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("event not found")
+
+var validID = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+/**
+Store keeps events as JSON files in a local directory, one file per event.
+*/
+type Store struct {
+	dir string
+	// Serialises read-modify-write cycles so concurrent requests don't drop each other's results.
+	mu sync.Mutex
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+/**
+Saves a new event, giving it an ID based on its name.
+*/
+func (s *Store) Create(event Event) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := slug(event.Name)
+	event.ID = base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(s.path(event.ID)); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		event.ID = fmt.Sprintf("%s-%d", base, i)
+	}
+	return event, s.save(event)
+}
+
+func (s *Store) Load(id string) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+/**
+Loads an event, applies update to it and saves it again unless update fails.
+*/
+func (s *Store) Update(id string, update func(*Event) error) (Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, err := s.load(id)
+	if err != nil {
+		return Event{}, err
+	}
+	if err := update(&event); err != nil {
+		return Event{}, err
+	}
+	return event, s.save(event)
+}
+
+/**
+Every stored event, sorted by ID.
+*/
+func (s *Store) List() ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	events := []Event{}
+	for _, v := range paths {
+		event, err := s.load(strings.TrimSuffix(filepath.Base(v), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *Store) load(id string) (Event, error) {
+	// IDs end up in file paths, so anything unexpected can't be a real event.
+	if !validID.MatchString(id) {
+		return Event{}, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Event{}, ErrNotFound
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, fmt.Errorf("could not read event %s: %w", id, err)
+	}
+	return event, nil
+}
+
+/**
+Writes to a temporary file first so that a crash mid write can't leave a truncated event behind.
+*/
+func (s *Store) save(event Event) error {
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path(event.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(event.ID))
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	id := strings.TrimSuffix(b.String(), "-")
+	if id == "" {
+		return "event"
+	}
+	return id
+}
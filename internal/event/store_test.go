@@ -0,0 +1,105 @@
+package event
+
+import (
+	"errors"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"reflect"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	event, _ := NewEvent("Turin Open #1", RoundRobin, []string{"A", "B", "C"}, 0, tournamentInfo)
+	created, err := store.Create(event)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	if created.ID != "turin-open-1" {
+		t.Errorf("Expected the ID to come from the name but got %s", created.ID)
+	}
+	again, _ := store.Create(event)
+	if again.ID != "turin-open-1-2" {
+		t.Errorf("Expected a clashing name to get a suffix but got %s", again.ID)
+	}
+
+	gameState := algo.GameState{
+		P2Rounds: []algo.P2Round{{Picks: []Faction{GC, KH}, Matchup: Matchup{P1: GC, P2: TZ}, WhoWon: P1}},
+		P3Round:  algo.P3Round{},
+	}
+	_, err = store.Update(created.ID, func(e *Event) error {
+		return e.RecordResult(0, 0, gameState, P1)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+
+	loaded, err := store.Load(created.ID)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	if !reflect.DeepEqual(loaded.TournamentInfo, tournamentInfo) {
+		t.Errorf("Expected tournament info to survive a round trip but got %+v", loaded.TournamentInfo)
+	}
+	if !reflect.DeepEqual(loaded.Rounds[0].Pairings[0].GameState, gameState) || loaded.Rounds[0].Pairings[0].Winner != P1 {
+		t.Errorf("Expected the recorded result to be saved but got %+v", loaded.Rounds[0].Pairings[0])
+	}
+
+	events, _ := store.List()
+	if len(events) != 2 {
+		t.Errorf("Expected 2 events but got %d", len(events))
+	}
+}
+
+func TestStoreFailedUpdateIsNotSaved(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+	event, _ := NewEvent("E", Swiss, []string{"A", "B", "C", "D"}, 2, tournamentInfo)
+	created, _ := store.Create(event)
+	_, err := store.Update(created.ID, func(e *Event) error {
+		e.Name = "Renamed"
+		return e.NextRound()
+	})
+	if err == nil {
+		t.Errorf("Expected pairing an unfinished round to fail")
+	}
+	loaded, _ := store.Load(created.ID)
+	if loaded.Name != "E" {
+		t.Errorf("Expected the failed update to be discarded but the name is %s", loaded.Name)
+	}
+}
+
+func TestStoreNotFound(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+	for _, id := range []string{"missing", "../etc/passwd", ""} {
+		if _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q but got %v", id, err)
+		}
+	}
+}
+
+func TestStoreRoundTripMaps(t *testing.T) {
+	store, _ := NewStore(t.TempDir())
+	mapInfo := TournamentInfo{
+		RoundCount:  3,
+		MatchupOdds: map[Matchup]float64{{P1: GC, P2: KH, Map: "Black Fortress"}: .7},
+		Ruleset:     Ruleset{Name: "Maps", MapPool: []GameMap{"Black Fortress", "Village"}, MapBansPerPlayer: 1},
+	}
+	for k, v := range MatchupsV1d2 {
+		mapInfo.MatchupOdds[k] = v
+	}
+	event, err := NewEvent("Maps", RoundRobin, []string{"A", "B"}, 0, mapInfo)
+	if err != nil {
+		t.Fatalf("Expected the event to be created but got %s", err)
+	}
+	created, _ := store.Create(event)
+	loaded, err := store.Load(created.ID)
+	if err != nil {
+		t.Fatalf("Expected no error but got %s", err)
+	}
+	if !reflect.DeepEqual(loaded.TournamentInfo, mapInfo) {
+		t.Errorf("Expected map odds and pool to survive a round trip but got %+v", loaded.TournamentInfo)
+	}
+}
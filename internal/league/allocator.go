@@ -0,0 +1,143 @@
+package league
+
+import (
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	"github.com/tmwilder/wh3-draftbot/internal/bracket"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+)
+
+/**
+Allocator searches a league's faction allocation for the line that gets the home side the most expected match points,
+against an away side out to keep them down. Every series is scored with Solver, MinimaxSolver if unset.
+*/
+type Allocator struct {
+	League League
+	Solver algo.Solver
+	// Depth is how many factions ahead to search, or every one of them if negative. Allocations that are cut off are
+	// scored by letting every player without all of their factions yet draft from the whole of what their side has
+	// left, as estimated by algo.PoolEvaluator.
+	Depth int
+
+	// matchupOdds are each board's odds adjusted for its players.
+	matchupOdds []map[Matchup]float64
+	boardOdds   map[string]float64
+	searched    map[string]searchResult
+}
+
+var emptyGameState = algo.GameState{P2Rounds: []algo.P2Round{}, P3Round: algo.P3Round{}}
+
+type searchResult struct {
+	value float64
+	line  Allocation
+}
+
+/**
+Returns the home side's expected match points and the line of allocations that gets them, through to the end of the
+search.
+*/
+func (a *Allocator) Solve(allocation Allocation) (float64, Allocation, error) {
+	if err := a.League.Validate(); err != nil {
+		return 0, Allocation{}, err
+	}
+	a.matchupOdds = nil
+	a.boardOdds = map[string]float64{}
+	a.searched = map[string]searchResult{}
+	value, line := a.search(allocation, a.Depth)
+	return value, line, nil
+}
+
+/**
+The home player's odds of winning the series on board, given the factions each player was allocated.
+*/
+func (a *Allocator) BoardWinProbability(board int, home []Faction, away []Faction) float64 {
+	solver := a.Solver
+	if solver == nil {
+		solver = algo.MinimaxSolver{}
+	}
+	odds, _ := solver.Solve(a.boardTournamentInfo(board, home, away), emptyGameState, true)
+	return odds
+}
+
+/**
+Allocations are searched with plain minimax rather than alpha beta so that every value is exact and can be reused:
+handing out the same factions in a different order leads to the same position, and that is most of the tree.
+*/
+func (a *Allocator) search(allocation Allocation, depth int) (float64, Allocation) {
+	league := a.League
+	if league.IsComplete(allocation) || depth == 0 {
+		return a.evaluate(allocation), allocation
+	}
+	key := fmt.Sprintf("%s%d", allocation.key(), depth)
+	if result, ok := a.searched[key]; ok {
+		return result.value, result.line
+	}
+
+	isHome := league.IsHomePickNext(allocation)
+	bestVal := math.Inf(1)
+	if isHome {
+		bestVal = math.Inf(-1)
+	}
+	var bestLine Allocation
+	for _, v := range league.successors(allocation) {
+		value, line := a.search(v, depth-1)
+		if (isHome && value > bestVal) || (!isHome && value < bestVal) {
+			bestVal, bestLine = value, line
+		}
+	}
+	a.searched[key] = searchResult{value: bestVal, line: bestLine}
+	return bestVal, bestLine
+}
+
+/**
+The home side's expected match points: the sum over boards of their odds of winning that board's series.
+*/
+func (a *Allocator) evaluate(allocation Allocation) float64 {
+	league := a.League
+	homeLeft := league.unallocated(allocation.Home)
+	awayLeft := league.unallocated(allocation.Away)
+
+	total := 0.0
+	for board := range league.Home {
+		home, away := allocation.Home[board], allocation.Away[board]
+		if len(home) == league.FactionsPerPlayer && len(away) == league.FactionsPerPlayer {
+			total += a.cachedBoardWinProbability(board, home, away)
+			continue
+		}
+		if len(home) < league.FactionsPerPlayer {
+			home = append(append([]Faction{}, home...), homeLeft...)
+		}
+		if len(away) < league.FactionsPerPlayer {
+			away = append(append([]Faction{}, away...), awayLeft...)
+		}
+		total += algo.PoolEvaluator(a.boardTournamentInfo(board, home, away), emptyGameState)
+	}
+	return total
+}
+
+func (a *Allocator) cachedBoardWinProbability(board int, home []Faction, away []Faction) float64 {
+	key := fmt.Sprintf("%d:%s", board, Allocation{Home: [][]Faction{home}, Away: [][]Faction{away}}.key())
+	if odds, ok := a.boardOdds[key]; ok {
+		return odds
+	}
+	odds := a.BoardWinProbability(board, home, away)
+	a.boardOdds[key] = odds
+	return odds
+}
+
+func (a *Allocator) boardTournamentInfo(board int, home []Faction, away []Faction) TournamentInfo {
+	tournamentInfo := a.League.TournamentInfo
+	if a.matchupOdds == nil {
+		for i := range a.League.Home {
+			a.matchupOdds = append(a.matchupOdds, bracket.PlayerMatchupOdds(tournamentInfo, a.League.Home[i], a.League.Away[i]))
+		}
+	}
+	return TournamentInfo{
+		RoundCount:  tournamentInfo.RoundCount,
+		MatchupOdds: a.matchupOdds[board],
+		Ruleset:     tournamentInfo.Ruleset,
+		P1Pool:      home,
+		P2Pool:      away,
+	}
+}
@@ -0,0 +1,184 @@
+package league
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tmwilder/wh3-draftbot/internal/bracket"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"sort"
+	"strings"
+)
+
+/**
+League is a team league match. Each side's players are paired off board by board and each pair plays one series under
+TournamentInfo, worth a match point to the side that wins it. Before any series is drafted the sides take turns, home
+first, giving one faction at a time to one of their players, and each player then drafts their series from what they
+were given. No faction can go to two players on the same side, but both sides can have it.
+A side can only field as many players as its pool has rosters for. Under the default Turin rules a Bo3 takes five
+factions per player, so the seven races alone are only enough for one player a side. Pools of lords, or rules that let
+players repeat factions, make room for more.
+*/
+type League struct {
+	TournamentInfo TournamentInfo
+	// Home and Away are the rosters in board order. Home plays as P1 on every board.
+	Home []bracket.Player
+	Away []bracket.Player
+	// FactionsPerPlayer is how many factions each player is given.
+	FactionsPerPlayer int
+}
+
+/**
+Allocation is what each side has given each of its players so far, by board.
+*/
+type Allocation struct {
+	Home [][]Faction
+	Away [][]Faction
+}
+
+func (l League) Validate() error {
+	if err := ValidateRoundCount(l.TournamentInfo.RoundCount); err != nil {
+		return err
+	}
+	if len(l.Home) == 0 || len(l.Home) != len(l.Away) {
+		return fmt.Errorf("both sides need the same number of players but got %d and %d", len(l.Home), len(l.Away))
+	}
+	// Every player's series has to be playable from their roster alone, under the same rule as any other series.
+	minimum := l.TournamentInfo.FactionsNeeded()
+	if l.FactionsPerPlayer < minimum {
+		return fmt.Errorf("a series of %d games needs at least %d factions per player but got %d", l.TournamentInfo.RoundCount, minimum, l.FactionsPerPlayer)
+	}
+	if pool := len(l.TournamentInfo.Ruleset.Pool()); len(l.Home)*l.FactionsPerPlayer > pool {
+		return fmt.Errorf("%d players with %d factions each need a pool of %d but it only has %d", len(l.Home), l.FactionsPerPlayer, len(l.Home)*l.FactionsPerPlayer, pool)
+	}
+	return nil
+}
+
+func (l League) NewAllocation() Allocation {
+	return Allocation{Home: make([][]Faction, len(l.Home)), Away: make([][]Faction, len(l.Away))}
+}
+
+func (l League) IsComplete(allocation Allocation) bool {
+	return allocated(allocation.Home) == len(l.Home)*l.FactionsPerPlayer &&
+		allocated(allocation.Away) == len(l.Away)*l.FactionsPerPlayer
+}
+
+func (l League) IsHomePickNext(allocation Allocation) bool {
+	return allocated(allocation.Home) == allocated(allocation.Away)
+}
+
+/**
+Gives faction to the player on board for whichever side picks next.
+*/
+func (l League) Allocate(allocation Allocation, board int, faction Faction) (Allocation, error) {
+	if l.IsComplete(allocation) {
+		return Allocation{}, errors.New("every player already has all of their factions")
+	}
+	isHome := l.IsHomePickNext(allocation)
+	hands := allocation.Away
+	if isHome {
+		hands = allocation.Home
+	}
+	if board < 0 || board >= len(hands) {
+		return Allocation{}, fmt.Errorf("no board %d", board)
+	}
+	if len(hands[board]) == l.FactionsPerPlayer {
+		return Allocation{}, fmt.Errorf("board %d already has all of its factions", board)
+	}
+	for _, v := range l.unallocated(hands) {
+		if v == faction {
+			return allocate(allocation, isHome, board, faction), nil
+		}
+	}
+	return Allocation{}, fmt.Errorf("%s isn't in the pool or has already been given out", faction)
+}
+
+func (l League) successors(allocation Allocation) []Allocation {
+	isHome := l.IsHomePickNext(allocation)
+	hands := allocation.Away
+	if isHome {
+		hands = allocation.Home
+	}
+	unallocated := l.unallocated(hands)
+
+	var successors []Allocation
+	for board, hand := range hands {
+		if len(hand) == l.FactionsPerPlayer {
+			continue
+		}
+		for _, faction := range unallocated {
+			successors = append(successors, allocate(allocation, isHome, board, faction))
+		}
+	}
+	return successors
+}
+
+func allocate(allocation Allocation, isHome bool, board int, faction Faction) Allocation {
+	successor := Allocation{Home: copyHands(allocation.Home), Away: copyHands(allocation.Away)}
+	if isHome {
+		successor.Home[board] = append(successor.Home[board], faction)
+	} else {
+		successor.Away[board] = append(successor.Away[board], faction)
+	}
+	return successor
+}
+
+/**
+The factions a side hasn't given to any of its players yet.
+*/
+func (l League) unallocated(hands [][]Faction) []Faction {
+	taken := map[Faction]bool{}
+	for _, hand := range hands {
+		for _, v := range hand {
+			taken[v] = true
+		}
+	}
+	var factions []Faction
+	for _, v := range l.TournamentInfo.Ruleset.Pool() {
+		if !taken[v] {
+			factions = append(factions, v)
+		}
+	}
+	return factions
+}
+
+func allocated(hands [][]Faction) int {
+	count := 0
+	for _, hand := range hands {
+		count += len(hand)
+	}
+	return count
+}
+
+func copyHands(hands [][]Faction) [][]Faction {
+	copied := make([][]Faction, len(hands))
+	for i, v := range hands {
+		copied[i] = append([]Faction{}, v...)
+	}
+	return copied
+}
+
+/**
+A key for an allocation that ignores the order factions were handed out in, since that can't change any series.
+*/
+func (a Allocation) key() string {
+	var b strings.Builder
+	for _, side := range [][][]Faction{a.Home, a.Away} {
+		for _, hand := range side {
+			for _, v := range sortedFactions(hand) {
+				b.WriteString(string(v))
+				b.WriteByte(',')
+			}
+			b.WriteByte('|')
+		}
+		b.WriteByte('/')
+	}
+	return b.String()
+}
+
+func sortedFactions(factions []Faction) []Faction {
+	sorted := append([]Faction{}, factions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
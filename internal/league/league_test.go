@@ -0,0 +1,165 @@
+package league
+
+import (
+	"github.com/tmwilder/wh3-draftbot/internal/algo"
+	"github.com/tmwilder/wh3-draftbot/internal/bracket"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
+	"testing"
+)
+
+const epsilon = .000001
+
+// The pool heuristic solves a draft instantly, which is all these tests need.
+var fastSolver = algo.DepthLimitedSolver{Depth: 0}
+
+func evenMatchups(pool []Faction) map[Matchup]float64 {
+	odds := map[Matchup]float64{}
+	for _, f1 := range pool {
+		for _, f2 := range pool {
+			odds[Matchup{P1: f1, P2: f2}] = .5
+		}
+	}
+	return odds
+}
+
+func player(name string, strengths map[Faction]float64) bracket.Player {
+	return bracket.Player{Name: name, FactionStrength: strengths}
+}
+
+/**
+Bo3 boards where every matchup is even, so only the players' strengths with the factions they are given matter.
+*/
+func newLeague(boards int, pool []Faction) League {
+	league := League{
+		TournamentInfo: TournamentInfo{
+			RoundCount:  3,
+			MatchupOdds: evenMatchups(pool),
+			Ruleset:     Ruleset{FactionPool: pool, Repeats: LordRepeats},
+		},
+		FactionsPerPlayer: 5,
+	}
+	for i := 0; i < boards; i++ {
+		league.Home = append(league.Home, player("Home", map[Faction]float64{}))
+		league.Away = append(league.Away, player("Away", map[Faction]float64{}))
+	}
+	return league
+}
+
+func TestValidate(t *testing.T) {
+	valid := newLeague(2, AllLords())
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid league but got: %s", err)
+	}
+
+	uneven := valid
+	uneven.Away = uneven.Away[:1]
+	tooFew := valid
+	tooFew.FactionsPerPlayer = 4
+	tooMany := valid
+	tooMany.FactionsPerPlayer = 6
+	for name, league := range map[string]League{"uneven": uneven, "too few": tooFew, "too many": tooMany} {
+		if err := league.Validate(); err == nil {
+			t.Errorf("Expected the %s league to be rejected", name)
+		}
+	}
+}
+
+func TestRosterLimits(t *testing.T) {
+	// The default rules take five factions per player for a Bo3, so the seven races only cover one board.
+	races := []Faction{GC, KH, KI, NG, OK, SL, TZ}
+	league := newLeague(1, races)
+	league.TournamentInfo.Ruleset.Repeats = RaceRepeats
+	if err := league.Validate(); err != nil {
+		t.Errorf("Expected one board on the races to be valid but got: %s", err)
+	}
+	twoBoards := newLeague(2, races)
+	twoBoards.TournamentInfo.Ruleset.Repeats = RaceRepeats
+	twoBoards.FactionsPerPlayer = 3
+	if err := twoBoards.Validate(); err == nil {
+		t.Errorf("Expected three factions per player to be too few without repeats")
+	}
+	// With repeats allowed, three each is enough, so two boards fit.
+	twoBoards.TournamentInfo.Ruleset.Repeats = AllowRepeats
+	if err := twoBoards.Validate(); err != nil {
+		t.Errorf("Expected two boards to be valid with repeats allowed but got: %s", err)
+	}
+	// Locking the winner out brings back the need for a faction per game.
+	twoBoards.TournamentInfo.Ruleset.WinnerLockedOut = true
+	if err := twoBoards.Validate(); err == nil {
+		t.Errorf("Expected three factions per player to be too few with the winner locked out")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	league := newLeague(2, AllLords())
+	allocation := league.NewAllocation()
+
+	allocation, err := league.Allocate(allocation, 0, Katarin)
+	if err != nil || len(allocation.Home[0]) != 1 || league.IsHomePickNext(allocation) {
+		t.Errorf("Expected home to give Katarin to board 0 and away to go next but got %+v, %v", allocation, err)
+	}
+	// Away can have Katarin too.
+	allocation, err = league.Allocate(allocation, 1, Katarin)
+	if err != nil || len(allocation.Away[1]) != 1 || !league.IsHomePickNext(allocation) {
+		t.Errorf("Expected away to give Katarin to board 1 and home to go next but got %+v, %v", allocation, err)
+	}
+	if _, err := league.Allocate(allocation, 1, Katarin); err == nil {
+		t.Errorf("Expected home not to be able to give Katarin to two players")
+	}
+	if _, err := league.Allocate(allocation, 0, KI); err == nil {
+		t.Errorf("Expected factions outside the pool to be rejected")
+	}
+	if _, err := league.Allocate(allocation, 2, Kostaltyn); err == nil {
+		t.Errorf("Expected a board that doesn't exist to be rejected")
+	}
+}
+
+func TestSolveGivesStrongFactionToBestPlayer(t *testing.T) {
+	league := newLeague(2, AllLords())
+	// Both home players are better with Skarbrand, but he is worth far more to the second.
+	league.Home[0].FactionStrength = map[Faction]float64{Skarbrand: 100}
+	league.Home[1].FactionStrength = map[Faction]float64{Skarbrand: 800}
+	// Everything but Skarbrand and Kairos is handed out already, so home has to choose who gets which.
+	allocation := Allocation{
+		Home: [][]Faction{{MiaoYing, ZhaoMing, Katarin, Kostaltyn}, {Kugath, Greasus, Skrag, NKari}},
+		Away: [][]Faction{{MiaoYing, ZhaoMing, Katarin, Kostaltyn}, {Kugath, Greasus, Skrag, NKari}},
+	}
+
+	allocator := Allocator{League: league, Solver: fastSolver, Depth: -1}
+	value, line, err := allocator.Solve(allocation)
+	if err != nil {
+		t.Fatalf("Expected the league to solve but got: %s", err)
+	}
+	if !league.IsComplete(line) {
+		t.Errorf("Expected a full search to allocate every faction but got %+v", line)
+	}
+	if line.Home[1][4] != Skarbrand {
+		t.Errorf("Expected Skarbrand to go to the second home player but got %+v", line.Home)
+	}
+
+	expected := 0.0
+	for board := range league.Home {
+		expected += allocator.BoardWinProbability(board, line.Home[board], line.Away[board])
+	}
+	if !(math.Abs(value-expected) < epsilon) || !(value > 1) {
+		t.Errorf("Expected the line to be worth %f match points, more than an even split, but got %f", expected, value)
+	}
+}
+
+func TestSolveDepthLimited(t *testing.T) {
+	league := newLeague(1, []Faction{GC, KH, KI, NG, OK, SL, TZ})
+	league.Away[0].FactionStrength = map[Faction]float64{NG: 400}
+
+	allocator := Allocator{League: league, Solver: fastSolver, Depth: 2}
+	value, line, err := allocator.Solve(league.NewAllocation())
+	if err != nil {
+		t.Fatalf("Expected the league to solve but got: %s", err)
+	}
+	if len(line.Home[0]) != 1 || len(line.Away[0]) != 1 {
+		t.Errorf("Expected two allocations to give one faction to each side but got %+v", line)
+	}
+	if !(value < .5) {
+		t.Errorf("Expected away's stronger player to be favoured but got %f", value)
+	}
+}
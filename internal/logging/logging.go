@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+Logger writes one line per event, each with a level, a message and any number of key value pairs. Lines are JSON
+objects for log collectors to read, or key=value text for people to. A Logger is safe for concurrent use.
+*/
+type Logger struct {
+	out io.Writer
+	// mu is shared with every Logger made from this one by With, as they all write to out.
+	mu     *sync.Mutex
+	isJSON bool
+	fields []interface{}
+	now    func() time.Time
+}
+
+/**
+A logger writing to out in format, which is json or text.
+*/
+func New(out io.Writer, format string) (*Logger, error) {
+	switch format {
+	case "json", "text":
+	default:
+		return nil, fmt.Errorf("log format should be json or text but got: %s", format)
+	}
+	return &Logger{out: out, mu: &sync.Mutex{}, isJSON: format == "json", now: time.Now}, nil
+}
+
+/**
+A logger that adds keyValues, alternating keys and values, to every line it writes.
+*/
+func (l *Logger) With(keyValues ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), keyValues...)
+	return &child
+}
+
+func (l *Logger) Info(msg string, keyValues ...interface{}) {
+	l.write("info", msg, keyValues)
+}
+
+func (l *Logger) Warn(msg string, keyValues ...interface{}) {
+	l.write("warn", msg, keyValues)
+}
+
+func (l *Logger) Error(msg string, keyValues ...interface{}) {
+	l.write("error", msg, keyValues)
+}
+
+func (l *Logger) write(level string, msg string, keyValues []interface{}) {
+	pairs := append([]interface{}{"time", l.now().UTC().Format(time.RFC3339Nano), "level", level, "msg", msg}, l.fields...)
+	pairs = append(pairs, keyValues...)
+	if len(pairs)%2 == 1 {
+		pairs = append(pairs, "")
+	}
+
+	var line strings.Builder
+	if l.isJSON {
+		line.WriteString("{")
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := fmt.Sprint(pairs[i]), pairs[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		if d, ok := value.(time.Duration); ok {
+			// Durations are logged in milliseconds so they can be compared and summed without parsing.
+			value = float64(d) / float64(time.Millisecond)
+		}
+		if l.isJSON {
+			if i > 0 {
+				line.WriteString(",")
+			}
+			writeJSON(&line, key)
+			line.WriteString(":")
+			writeJSON(&line, value)
+		} else {
+			if i > 0 {
+				line.WriteString(" ")
+			}
+			line.WriteString(key + "=" + textValue(value))
+		}
+	}
+	if l.isJSON {
+		line.WriteString("}")
+	}
+	line.WriteString("\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line.String())
+}
+
+func writeJSON(line *strings.Builder, value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		bytes, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(bytes)
+}
+
+/**
+Values are quoted where they would otherwise run into the next pair.
+*/
+func textValue(value interface{}) string {
+	text := fmt.Sprint(value)
+	if text == "" || strings.ContainsAny(text, " =\"\t\n") {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func fixedTime() time.Time {
+	return time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func TestJSONLine(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.now = fixedTime
+
+	logger.With("requestId", "abc").Info("search", "nodes", 42, "durationMs", 1500*time.Microsecond, "err", errors.New("bad odds"))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line but got %s", out.String())
+	}
+	expected := map[string]interface{}{
+		"time":       "2022-06-01T12:00:00Z",
+		"level":      "info",
+		"msg":        "search",
+		"requestId":  "abc",
+		"nodes":      42.0,
+		"durationMs": 1.5,
+		"err":        "bad odds",
+	}
+	for k, v := range expected {
+		if line[k] != v {
+			t.Errorf("Expected %s to be %v but got %v", k, v, line[k])
+		}
+	}
+}
+
+func TestTextLine(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.now = fixedTime
+
+	logger.Warn("bad input", "input", "rounds=x y", "count", 3)
+
+	expected := "time=2022-06-01T12:00:00Z level=warn msg=\"bad input\" input=\"rounds=x y\" count=3\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, out.String())
+	}
+}
+
+func TestWithDoesNotChangeParent(t *testing.T) {
+	var out bytes.Buffer
+	logger, _ := New(&out, "text")
+	logger.now = fixedTime
+
+	logger.With("requestId", "abc")
+	logger.Info("done")
+
+	expected := "time=2022-06-01T12:00:00Z level=info msg=done\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, out.String())
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
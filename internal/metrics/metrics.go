@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+Registry holds counters, gauges and histograms and writes them out in Prometheus' text format, so a Prometheus server
+can scrape them without the server needing its client library. A Registry is safe for concurrent use.
+
+Each metric is declared once with its help text, then updated by name with its labels given as alternating names and
+values. Every update to a metric must give the same label names in the same order.
+*/
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	name string
+	help string
+	kind string
+	// buckets are the upper bounds of a histogram's buckets, smallest first, leaving out +Inf.
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labels string
+	value  float64
+	// counts are a histogram's observations per bucket, not cumulative, with the last one for +Inf.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// LatencyBuckets suit request and search times in seconds, from a millisecond to a couple of minutes.
+var LatencyBuckets = []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// NodeBuckets suit how many positions a search visits.
+var NodeBuckets = []float64{10, 100, 1000, 1e4, 1e5, 1e6, 1e7, 1e8}
+
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// Counter declares a metric that only goes up.
+func (r *Registry) Counter(name string, help string) {
+	r.declare(&family{name: name, help: help, kind: "counter"})
+}
+
+// Gauge declares a metric that can go up and down.
+func (r *Registry) Gauge(name string, help string) {
+	r.declare(&family{name: name, help: help, kind: "gauge"})
+}
+
+// Histogram declares a metric that counts observations into buckets.
+func (r *Registry) Histogram(name string, help string, buckets []float64) {
+	r.declare(&family{name: name, help: help, kind: "histogram", buckets: buckets})
+}
+
+func (r *Registry) declare(f *family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f.series = map[string]*series{}
+	r.families[f.name] = f
+}
+
+/**
+Adds value to a counter or gauge.
+*/
+func (r *Registry) Add(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, labels).value += value
+}
+
+/**
+Sets a gauge, or a counter kept elsewhere that is only copied in before being written out.
+*/
+func (r *Registry) Set(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(name, labels).value = value
+}
+
+/**
+Counts value into a histogram.
+*/
+func (r *Registry) Observe(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.get(name, labels)
+	f := r.families[name]
+	bucket := sort.SearchFloat64s(f.buckets, value)
+	s.counts[bucket]++
+	s.sum += value
+	s.count++
+}
+
+/**
+The series of name for labels, created on first use. Panics if name was never declared, as that can only be a typo.
+*/
+func (r *Registry) get(name string, labels []string) *series {
+	f, ok := r.families[name]
+	if !ok {
+		panic("Unknown metric: " + name)
+	}
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+/**
+Writes every metric in Prometheus' text format, sorted by name and labels so the output is stable.
+*/
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out strings.Builder
+	names := make([]string, 0, len(r.families))
+	for k := range r.families {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(f.help), name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s := f.series[k]
+			if f.kind != "histogram" {
+				fmt.Fprintf(&out, "%s%s %s\n", name, braces(s.labels), formatValue(s.value))
+				continue
+			}
+			cumulative := uint64(0)
+			for i, bound := range append(append([]float64{}, f.buckets...), math.Inf(1)) {
+				cumulative += s.counts[i]
+				le := "le=\"" + formatValue(bound) + "\""
+				fmt.Fprintf(&out, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, le)), cumulative)
+			}
+			fmt.Fprintf(&out, "%s_sum%s %s\n", name, braces(s.labels), formatValue(s.sum))
+			fmt.Fprintf(&out, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
+
+func formatLabels(labels []string) string {
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"=\""+escapeLabel(labels[i+1])+"\"")
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels string, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(help)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("requests_total", "Requests served.")
+	registry.Gauge("cache_entries", "Results in memory.")
+
+	registry.Add("requests_total", 1, "route", "/view", "status", "200")
+	registry.Add("requests_total", 2, "route", "/view", "status", "200")
+	registry.Add("requests_total", 1, "route", "/recommend/", "status", "500")
+	registry.Set("cache_entries", 7)
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+
+	expected := `# HELP cache_entries Results in memory.
+# TYPE cache_entries gauge
+cache_entries 7
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/recommend/",status="500"} 1
+requests_total{route="/view",status="200"} 3
+`
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, out.String())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	registry := NewRegistry()
+	registry.Histogram("duration_seconds", "How long it took.", []float64{.1, 1})
+
+	registry.Observe("duration_seconds", .05, "route", "/view")
+	registry.Observe("duration_seconds", .1, "route", "/view")
+	registry.Observe("duration_seconds", .5, "route", "/view")
+	registry.Observe("duration_seconds", 3, "route", "/view")
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+
+	expected := `# HELP duration_seconds How long it took.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/view",le="0.1"} 2
+duration_seconds_bucket{route="/view",le="1"} 3
+duration_seconds_bucket{route="/view",le="+Inf"} 4
+duration_seconds_sum{route="/view"} 3.65
+duration_seconds_count{route="/view"} 4
+`
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, out.String())
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("errors_total", "Errors.")
+
+	registry.Add("errors_total", 1, "path", "a\"b\\c\nd")
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+
+	expected := `errors_total{path="a\"b\\c\nd"} 1`
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected %s in\n%s", expected, out.String())
+	}
+}
@@ -2,16 +2,108 @@ package app
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/tmwilder/wh3-draftbot/internal/cache"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"github.com/tmwilder/wh3-draftbot/internal/config"
+	"github.com/tmwilder/wh3-draftbot/internal/event"
+	"github.com/tmwilder/wh3-draftbot/internal/logging"
+	"github.com/tmwilder/wh3-draftbot/internal/web"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 )
 
-func App() {
-	r := gin.Default()
-	r.GET("/view", viewHandler)
-	r.GET("/recommend/", recommendHandler)
-	r.LoadHTMLGlob("internal/web/template/*")
+// Set from the config when the app starts.
+var (
+	defaultRuleset     Ruleset
+	defaultMatchupOdds map[Matchup]float64
+	searchBudget       time.Duration
+	maxRoundCount      int
+)
 
-	err := r.Run()
+func App(cfg config.Config) {
+	setUp(cfg)
+	r := newRouter(cfg)
+
+	logger.Info("listening", "addr", cfg.Addr, "mode", cfg.Mode, "ruleset", cfg.Ruleset, "searchBudget", searchBudget, "openingBooks", len(openingBooks))
+	err := r.Run(cfg.Addr)
 	if err != nil {
 		panic("Could not start web server: " + err.Error())
 	}
 }
+
+/**
+Sets up everything the handlers share from cfg.
+*/
+func setUp(cfg config.Config) {
+	defaultRuleset = Rulesets[cfg.Ruleset]
+	matchupOdds, err := cfg.MatchupOdds()
+	if err != nil {
+		panic("Could not load matchup odds: " + err.Error())
+	}
+	defaultMatchupOdds = matchupOdds
+	searchBudget = cfg.SearchBudget.Duration
+	maxRoundCount = cfg.MaxRoundCount
+	logger, err = logging.New(os.Stderr, cfg.LogFormat)
+	if err != nil {
+		panic("Could not set up logging: " + err.Error())
+	}
+
+	store, err := event.NewStore(cfg.EventDir)
+	if err != nil {
+		panic("Could not open event store: " + err.Error())
+	}
+	eventStore = store
+	searchCache, err = cache.New(cfg.CacheSize, cfg.CacheDir)
+	if err != nil {
+		panic("Could not open search cache: " + err.Error())
+	}
+	openingBooks, err = loadOpeningBooks(cfg.BookDir)
+	if err != nil {
+		panic("Could not load opening books: " + err.Error())
+	}
+}
+
+func newRouter(cfg config.Config) *gin.Engine {
+	gin.SetMode(cfg.Mode)
+	r := gin.New()
+	r.Use(observeRequests(cfg.AccessLog))
+	r.Use(recovery)
+	r.GET("/view", viewHandler)
+	r.GET("/recommend/", recommendHandler)
+	r.GET("/review", reviewHandler)
+	r.GET("/events", eventsHandler)
+	r.POST("/events", createEventHandler)
+	r.GET("/events/:id", eventHandler)
+	r.POST("/events/:id/rounds", nextRoundHandler)
+	r.POST("/events/:id/record", recordResultHandler)
+	r.GET("/api/recommend", recommendAPIHandler)
+	r.GET("/api/cache", cacheStatsAPIHandler)
+	r.GET("/metrics", metricsHandler)
+	r.GET("/api/events", listEventsAPIHandler)
+	r.POST("/api/events", createEventAPIHandler)
+	r.GET("/api/events/:id", eventAPIHandler)
+	r.POST("/api/events/:id/rounds", nextRoundAPIHandler)
+	r.POST("/api/events/:id/results", recordResultAPIHandler)
+	// Templates and static files are built in, unless the config points at copies on disk to work on them live.
+	funcMap := template.FuncMap{"joinMaps": joinMaps, "joinPicks": joinPicks, "factionPoolOverride": factionPoolOverride}
+	if cfg.TemplateDir != "" {
+		r.SetFuncMap(funcMap)
+		r.LoadHTMLGlob(filepath.Join(cfg.TemplateDir, "*"))
+	} else {
+		r.SetHTMLTemplate(template.Must(template.New("").Funcs(funcMap).ParseFS(web.Files, "template/*")))
+	}
+	if cfg.StaticDir != "" {
+		r.Static("/static", cfg.StaticDir)
+	} else {
+		static, err := fs.Sub(web.Files, "static")
+		if err != nil {
+			panic("Could not find static files: " + err.Error())
+		}
+		r.StaticFS("/static", http.FS(static))
+	}
+	return r
+}
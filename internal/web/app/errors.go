@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// inputError is a query parameter the draft can't be read from.
+type inputError struct {
+	Param string
+	Err   error
+}
+
+func (e *inputError) Error() string {
+	return fmt.Sprintf("could not read %s: %s", e.Param, e.Err)
+}
+
+func (e *inputError) Unwrap() error {
+	return e.Err
+}
+
+/**
+Turns a panic in a handler into a 500 rather than a dropped connection, and logs it with the request's ID and where it
+happened. API clients get the error as JSON, everyone else a fresh draft page with it on top.
+*/
+func recovery(c *gin.Context) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		registry.Add("draftbot_errors_total", 1, "kind", "panic")
+		requestLogger(c).Error("panic", "err", fmt.Sprint(r), "stack", string(debug.Stack()))
+		message := "something went wrong on our side, please try again or report the link that did it"
+		if c.Writer.Written() {
+			c.Abort()
+		} else if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": message})
+		} else {
+			tournamentInfo, gameState := applyDefaults(TournamentInfo{RoundCount: 3, MatchupOdds: defaultMatchupOdds, Ruleset: defaultRuleset}, GameState{})
+			c.HTML(http.StatusInternalServerError, "draftbot.html", pageData{
+				TournamentInfo: tournamentInfo,
+				GameState:      gameState,
+				Rulesets:       Rulesets,
+				Error:          message,
+			})
+			c.Abort()
+		}
+	}()
+	c.Next()
+}
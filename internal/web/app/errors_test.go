@@ -0,0 +1,189 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"github.com/tmwilder/wh3-draftbot/internal/config"
+	"github.com/tmwilder/wh3-draftbot/internal/logging"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testRouter(t *testing.T) *gin.Engine {
+	cfg := config.Default()
+	cfg.Mode = gin.TestMode
+	cfg.AccessLog = false
+	cfg.EventDir = t.TempDir()
+	setUp(cfg)
+	logger, _ = logging.New(io.Discard, "text")
+	return newRouter(cfg)
+}
+
+func get(r *gin.Engine, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// The start of a Bo3 under the default rules, to add bad input to.
+const emptyDraft = "/recommend/?rounds=3&ruleset=2022-Q2-Turin-Default"
+
+func TestBadInputIsABadRequest(t *testing.T) {
+	r := testRouter(t)
+	pool := joinPicks(TournamentInfo{Ruleset: Turin2022Q2}.PlayerPool(true))
+
+	for name, test := range map[string]struct {
+		query    string
+		expected string
+	}{
+		"odds that aren't a number":            {"&odds-GC-KH=abc", "could not read odds-GC-KH: &#34;abc&#34; isn&#39;t a number"},
+		"odds over 1":                          {"&odds-GC-KH=1.5", "odds should be between 0 and 1 but got 1.5"},
+		"extra odds that aren't a number":      {"&extra-odds=GC-KH%3Dabc", "could not read extra-odds: GC-KH: &#34;abc&#34; isn&#39;t a number"},
+		"an odds key too short to read":        {"&odds-GC=0.5", "could not read odds-GC: &#34;GC&#34; isn&#39;t a matchup"},
+		"a pool without odds":                  {"&faction-pool=" + strings.ReplaceAll(pool+" XX", " ", "+"), "there are no odds for"},
+		"an unknown faction":                   {"&picks=SL+XX", "the initial picks in game 1 isn&#39;t legal"},
+		"a faction listed twice":               {"&faction-pool=GC+GC+KH+KI+NG+OK", "GC is in the faction pool more than once"},
+		"a final pick before the counter pick": {"&picks=SL+TZ&p1pick=TZ", "the counter pick in game 1 isn&#39;t legal"},
+		"a winner who isn't a player":          {"&picks=SL+TZ&p2pick=SL&p1pick=TZ&whowon=P3", "could not read whowon: game 1 was won by P1 or P2, or no one yet, but got &#34;P3&#34;"},
+		"an unknown repeat rule":               {"&repeats=bogus", "could not read repeats: &#34;bogus&#34; isn&#39;t one of &#34;race&#34;, &#34;lord&#34;, &#34;allowed&#34;"},
+		"an unknown first pick rule":           {"&first-pick=bogus", "could not read first-pick: &#34;bogus&#34; isn&#39;t one of &#34;alternate&#34;, &#34;loser&#34;, &#34;winner&#34;"},
+		"an unknown map picker":                {"&map-picker=bogus", "could not read map-picker: &#34;bogus&#34; isn&#39;t one of &#34;first-picker&#34;, &#34;counter-picker&#34;"},
+		"negative global bans":                 {"&global-bans-per-player=-3", "could not read global-bans-per-player: bans per player can&#39;t be negative but got -3"},
+		"global bans that aren't a number":     {"&global-bans-per-player=two", "could not read global-bans-per-player: &#34;two&#34; isn&#39;t a number"},
+		"negative map bans":                    {"&map-pool=Zharr-Naggrund%2C+Black+Fortress&map-bans-per-player=-1", "could not read map-bans-per-player: bans per player can&#39;t be negative but got -1"},
+		"map bans that aren't a number":        {"&map-bans-per-player=two", "could not read map-bans-per-player: &#34;two&#34; isn&#39;t a number"},
+		"more map bans than the pool allows":   {"&map-pool=Zharr-Naggrund%2C+Black+Fortress&map-bans-per-player=5", "could not read map-bans-per-player: a pool of 2 maps leaves nothing to play after 5 bans each"},
+		"extra odds that aren't a matchup":     {"&extra-odds=GC+beats+KH", "could not read extra-odds: &#34;GC beats KH&#34; isn&#39;t a matchup and odds, e.g. GC-KH=0.55"},
+		"extra odds without odds":              {"&extra-odds=GC-KH%0AGC-KI%3D0.5", "could not read extra-odds: &#34;GC-KH&#34; isn&#39;t a matchup and odds, e.g. GC-KH=0.55"},
+	} {
+		w := get(r, emptyDraft+test.query)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected %s to be a bad request but got %d", name, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `<div class="alert alert-danger" role="alert">`) || !strings.Contains(w.Body.String(), test.expected) {
+			t.Errorf("Expected %s to show %s", name, test.expected)
+		}
+	}
+}
+
+func TestBadRoundsAndPoints(t *testing.T) {
+	r := testRouter(t)
+
+	for name, test := range map[string]struct {
+		query    string
+		expected string
+	}{
+		"an even round count":     {"rounds=4", "could not read rounds: series must be best of an odd number of games from 1 to 9 but got 4"},
+		"a round count over 9":    {"rounds=11", "could not read rounds: series must be best of an odd number of games from 1 to 9 but got 11"},
+		"a round count not given": {"rounds=", `could not read rounds: "" isn't a number`},
+		"a score that isn't one":  {"rounds=3&objective=points&points=2-0%3D3%2C+2%3D2", `could not read points: "2=2" isn't a score and points, e.g. 2-0=3`},
+		"points that aren't one":  {"rounds=3&objective=points&points=2-0%3Dlots", `could not read points: "2-0=lots" isn't a score and points, e.g. 2-0=3`},
+		"no points at all":        {"rounds=3&objective=points&points=", "could not read points: a points table needs points for at least one score, e.g. 2-0=3"},
+		"a risk penalty of Inf":   {"rounds=3&risk-penalty=Inf", `could not read risk-penalty: "Inf" isn't a finite number`},
+		"a risk penalty of NaN":   {"rounds=3&risk-penalty=NaN", `could not read risk-penalty: "NaN" isn't a finite number`},
+		"a negative risk penalty": {"rounds=3&risk-penalty=-1", "could not read risk-penalty: a risk penalty can't be negative but got -1"},
+		"a risk penalty of lots":  {"rounds=3&risk-penalty=lots", `could not read risk-penalty: "lots" isn't a number`},
+	} {
+		for _, page := range []string{"/view?", "/recommend/?", "/review?"} {
+			w := get(r, page+test.query)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected %s on %s to be a bad request but got %d", name, page, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), html.EscapeString(test.expected)) {
+				t.Errorf("Expected %s on %s to show %s", name, page, test.expected)
+			}
+		}
+
+		w := get(r, "/api/recommend?"+test.query)
+		var body map[string]string
+		if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &body) != nil || body["error"] != test.expected {
+			t.Errorf("Expected %s to be a bad request to the API but got %d %s", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBadThreshold(t *testing.T) {
+	r := testRouter(t)
+	review := "/review?rounds=1&last-picks=GC+KI+NG&last-ban=KI"
+
+	for threshold, expected := range map[string]string{
+		"abc": "could not read threshold: &#34;abc&#34; isn&#39;t a number",
+		"-1":  "could not read threshold: the threshold should be between 0 and 1 but got -1",
+	} {
+		w := get(r, review+"&threshold="+threshold)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Expected threshold %s to be a bad request showing %s but got %d", threshold, expected, w.Code)
+		}
+	}
+	if w := get(r, review+"&threshold="); w.Code != http.StatusOK {
+		t.Errorf("Expected a review without a threshold to use the default but got %d", w.Code)
+	}
+}
+
+func TestBadInputKeepsTheForm(t *testing.T) {
+	r := testRouter(t)
+
+	w := get(r, "/view?rounds=3&ruleset=2022-Q2-Turin-Default&odds-GC-KH=abc&picks=SL+TZ&p1pick=TZ&p2pick=GC")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a bad request but got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `value="SL TZ"`) {
+		t.Errorf("Expected the picks to be filled back in")
+	}
+}
+
+func TestBadInputToTheAPI(t *testing.T) {
+	r := testRouter(t)
+
+	w := get(r, "/api/recommend?rounds=3&odds-GC-KH=abc")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a bad request but got %d", w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error but got %s", w.Body.String())
+	}
+	expected := `could not read odds-GC-KH: "abc" isn't a number`
+	if body["error"] != expected {
+		t.Errorf("Expected %q but got %q", expected, body["error"])
+	}
+}
+
+func TestMissingRoundFields(t *testing.T) {
+	r := testRouter(t)
+
+	// A hand written link with picks for a game but no matchup for it.
+	w := get(r, "/view?rounds=3&picks=SL+TZ")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the draft to be read but got %d", w.Code)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	r := testRouter(t)
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/api/panic", func(c *gin.Context) { panic("boom") })
+
+	w := get(r, "/panic")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected a server error but got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "something went wrong on our side") {
+		t.Errorf("Expected the draft page to say something went wrong")
+	}
+
+	w = get(r, "/api/panic")
+	var body map[string]string
+	if w.Code != http.StatusInternalServerError || json.Unmarshal(w.Body.Bytes(), &body) != nil || body["error"] == "" {
+		t.Errorf("Expected a JSON server error but got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := get(r, "/view"); w.Code != http.StatusOK {
+		t.Errorf("Expected the server to carry on after a panic but got %d", w.Code)
+	}
+}
@@ -0,0 +1,385 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"github.com/tmwilder/wh3-draftbot/internal/event"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var eventStore *event.Store
+
+type eventsPageData struct {
+	Events   []event.Event
+	Rulesets map[string]Ruleset
+	Error    string
+}
+
+type eventPageData struct {
+	Event     event.Event
+	Standings []event.Standing
+	// DraftLinks[round][pairing] opens the pairing's draft.
+	DraftLinks [][]string
+	Error      string
+}
+
+/**
+draftEvent is the event match a draft session belongs to, if it was opened from an event.
+*/
+type draftEvent struct {
+	ID      string
+	Name    string
+	Round   int
+	Pairing int
+	P1      string
+	P2      string
+	Winner  WhoWon
+}
+
+type createEventRequest struct {
+	Name        string
+	Format      event.Format
+	Players     []string
+	SwissRounds int
+	RoundCount  int
+	Ruleset     string
+	// MatchupOdds defaults to the server's matchup odds where not given.
+	MatchupOdds      map[Matchup]float64
+	MapPool          []GameMap
+	MapBansPerPlayer int
+	MapPicker        MapPicker
+	// FactionPool, Repeats, GlobalBansPerPlayer, FirstPick, WinnerLockedOut and StopWhenDecided override the
+	// ruleset's when set.
+	FactionPool         []Faction
+	Repeats             RepeatGranularity
+	GlobalBansPerPlayer int
+	FirstPick           FirstPickRule
+	WinnerLockedOut     bool
+	StopWhenDecided     bool
+}
+
+type recordResultRequest struct {
+	Round     int
+	Pairing   int
+	Winner    WhoWon
+	GameState GameState
+}
+
+type eventResponse struct {
+	Event     event.Event
+	Standings []event.Standing
+}
+
+func eventsHandler(c *gin.Context) {
+	events, err := eventStore.List()
+	pageData := eventsPageData{Events: events, Rulesets: Rulesets}
+	if err != nil {
+		pageData.Error = err.Error()
+	}
+	c.HTML(http.StatusOK, "events.html", pageData)
+}
+
+func createEventHandler(c *gin.Context) {
+	swissRounds, _ := strconv.Atoi(c.PostForm("swiss-rounds"))
+	roundCount, _ := strconv.Atoi(c.PostForm("rounds"))
+	mapBans, _ := strconv.Atoi(c.PostForm("map-bans-per-player"))
+	var players []string
+	for _, v := range strings.Split(c.PostForm("players"), "\n") {
+		if player := strings.TrimSpace(v); player != "" {
+			players = append(players, player)
+		}
+	}
+
+	created, err := createEvent(createEventRequest{
+		Name:             strings.TrimSpace(c.PostForm("name")),
+		Format:           event.Format(c.PostForm("format")),
+		Players:          players,
+		SwissRounds:      swissRounds,
+		RoundCount:       roundCount,
+		Ruleset:          c.PostForm("ruleset"),
+		MapPool:          parseMaps(c.PostForm("map-pool")),
+		MapBansPerPlayer: mapBans,
+		MapPicker:        MapPicker(c.PostForm("map-picker")),
+	})
+	if err != nil {
+		events, _ := eventStore.List()
+		c.HTML(http.StatusBadRequest, "events.html", eventsPageData{Events: events, Rulesets: Rulesets, Error: err.Error()})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/events/"+created.ID)
+}
+
+func eventHandler(c *gin.Context) {
+	e, err := eventStore.Load(c.Param("id"))
+	if err != nil {
+		c.String(errorStatus(err), err.Error())
+		return
+	}
+	renderEvent(c, http.StatusOK, e, "")
+}
+
+func nextRoundHandler(c *gin.Context) {
+	e, err := eventStore.Update(c.Param("id"), func(e *event.Event) error {
+		return e.NextRound()
+	})
+	if errors.Is(err, event.ErrNotFound) {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		current, _ := eventStore.Load(c.Param("id"))
+		renderEvent(c, http.StatusBadRequest, current, err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/events/"+e.ID)
+}
+
+/**
+Saves a draft submitted from the draft page back to the event it was opened from.
+*/
+func recordResultHandler(c *gin.Context) {
+	_, gameState, _, inputErr := parseInputs(c)
+	// The draft is checked against the event's rules as it is recorded, not against whatever rules the form sent.
+	var formErr *inputError
+	if !errors.As(inputErr, &formErr) {
+		inputErr = nil
+	}
+	round, _ := strconv.Atoi(c.PostForm("event-round"))
+	pairing, _ := strconv.Atoi(c.PostForm("event-pairing"))
+	winner := WhoWon(c.PostForm("event-winner"))
+
+	_, err := eventStore.Update(c.Param("id"), func(e *event.Event) error {
+		if inputErr != nil {
+			return inputErr
+		}
+		return e.RecordResult(round, pairing, gameState, winner)
+	})
+	if errors.Is(err, event.ErrNotFound) {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		current, _ := eventStore.Load(c.Param("id"))
+		renderEvent(c, http.StatusBadRequest, current, err.Error())
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/events/"+c.Param("id"))
+}
+
+func renderEvent(c *gin.Context, status int, e event.Event, errorMessage string) {
+	draftLinks := make([][]string, len(e.Rounds))
+	for r, round := range e.Rounds {
+		for p := range round.Pairings {
+			draftLinks[r] = append(draftLinks[r], draftLink(e, r, p))
+		}
+	}
+	c.HTML(status, "event.html", eventPageData{
+		Event:      e,
+		Standings:  e.Standings(),
+		DraftLinks: draftLinks,
+		Error:      errorMessage,
+	})
+}
+
+func listEventsAPIHandler(c *gin.Context) {
+	events, err := eventStore.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+func createEventAPIHandler(c *gin.Context) {
+	var request createEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := createEvent(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, eventResponse{Event: created, Standings: created.Standings()})
+}
+
+func eventAPIHandler(c *gin.Context) {
+	e, err := eventStore.Load(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, eventResponse{Event: e, Standings: e.Standings()})
+}
+
+func nextRoundAPIHandler(c *gin.Context) {
+	e, err := eventStore.Update(c.Param("id"), func(e *event.Event) error {
+		return e.NextRound()
+	})
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, eventResponse{Event: e, Standings: e.Standings()})
+}
+
+func recordResultAPIHandler(c *gin.Context) {
+	var request recordResultRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e, err := eventStore.Update(c.Param("id"), func(e *event.Event) error {
+		return e.RecordResult(request.Round, request.Pairing, request.GameState, request.Winner)
+	})
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, eventResponse{Event: e, Standings: e.Standings()})
+}
+
+func createEvent(request createEventRequest) (event.Event, error) {
+	if request.RoundCount > maxRoundCount {
+		return event.Event{}, fmt.Errorf("this server drafts at most %d rounds", maxRoundCount)
+	}
+	ruleset, ok := Rulesets[request.Ruleset]
+	if !ok {
+		ruleset = defaultRuleset
+	}
+	if len(request.FactionPool) > 0 {
+		ruleset.FactionPool = request.FactionPool
+	}
+	if request.Repeats != "" {
+		ruleset.Repeats = request.Repeats
+	}
+	if request.GlobalBansPerPlayer > 0 {
+		ruleset.GlobalBansPerPlayer = request.GlobalBansPerPlayer
+	}
+	if request.FirstPick != "" {
+		ruleset.FirstPick = request.FirstPick
+	}
+	if request.WinnerLockedOut {
+		ruleset.WinnerLockedOut = true
+	}
+	if request.StopWhenDecided {
+		ruleset.StopWhenDecided = true
+	}
+	ruleset.MapPool = request.MapPool
+	ruleset.MapBansPerPlayer = request.MapBansPerPlayer
+	ruleset.MapPicker = request.MapPicker
+	matchupOdds := map[Matchup]float64{}
+	for k, v := range defaultMatchupOdds {
+		matchupOdds[k] = v
+	}
+	for k, v := range request.MatchupOdds {
+		matchupOdds[k] = v
+	}
+	tournamentInfo := TournamentInfo{RoundCount: request.RoundCount, MatchupOdds: matchupOdds, Ruleset: ruleset}
+
+	e, err := event.NewEvent(request.Name, request.Format, request.Players, request.SwissRounds, tournamentInfo)
+	if err != nil {
+		return event.Event{}, err
+	}
+	return eventStore.Create(e)
+}
+
+/**
+Anything other than a missing event is down to a bad request, since the store only fails on the disk otherwise.
+*/
+func errorStatus(err error) int {
+	if errors.Is(err, event.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+/**
+A link to the draft page set up with the event's tournament info and whatever has been recorded of the pairing's
+draft so far - the inverse of parseInputs.
+*/
+func draftLink(e event.Event, round int, pairing int) string {
+	query := url.Values{}
+	query.Set("rounds", strconv.Itoa(e.TournamentInfo.RoundCount))
+	query.Set("ruleset", e.TournamentInfo.Ruleset.Name)
+	query.Set("faction-pool", joinPicks(e.TournamentInfo.Ruleset.FactionPool))
+	query.Set("repeats", string(e.TournamentInfo.Ruleset.Repeats))
+	query.Set("global-bans-per-player", strconv.Itoa(e.TournamentInfo.Ruleset.GlobalBansPerPlayer))
+	query.Set("first-pick", string(e.TournamentInfo.Ruleset.FirstPick))
+	query.Set("winner-locked-out", strconv.FormatBool(e.TournamentInfo.Ruleset.WinnerLockedOut))
+	query.Set("stop-when-decided", strconv.FormatBool(e.TournamentInfo.Ruleset.StopWhenDecided))
+	query.Set("map-pool", joinMaps(e.TournamentInfo.Ruleset.MapPool))
+	query.Set("map-bans-per-player", strconv.Itoa(e.TournamentInfo.Ruleset.MapBansPerPlayer))
+	query.Set("map-picker", string(e.TournamentInfo.Ruleset.MapPicker))
+	for k, v := range e.TournamentInfo.MatchupOdds {
+		key, _ := k.MarshalText()
+		query.Set("odds-"+string(key), strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	gameState := e.Rounds[round].Pairings[pairing].GameState
+	for _, v := range gameState.P2Rounds {
+		query.Add("picks", joinPicks(v.Picks))
+		query.Add("p1pick", string(v.Matchup.P1))
+		query.Add("p2pick", string(v.Matchup.P2))
+		query.Add("whowon", string(v.WhoWon))
+		query.Add("map", string(v.Matchup.Map))
+	}
+	query.Set("p1-bans", joinPicks(gameState.P1Bans))
+	query.Set("p2-bans", joinPicks(gameState.P2Bans))
+	query.Set("banned-maps", joinMaps(gameState.MapBans))
+	query.Set("last-map", string(gameState.P3Round.Matchup.Map))
+	query.Set("last-picks", joinPicks(gameState.P3Round.Picks))
+	query.Set("last-ban", string(gameState.P3Round.Ban))
+	query.Set("last-counter-ban", string(gameState.P3Round.CounterBan))
+	query.Set("last-p1pick", string(gameState.P3Round.Matchup.P1))
+	query.Set("last-p2pick", string(gameState.P3Round.Matchup.P2))
+
+	query.Set("event", e.ID)
+	query.Set("event-round", strconv.Itoa(round))
+	query.Set("event-pairing", strconv.Itoa(pairing))
+	return "/view?" + query.Encode()
+}
+
+func joinPicks(picks []Faction) string {
+	var factions []string
+	for _, v := range picks {
+		factions = append(factions, string(v))
+	}
+	return strings.Join(factions, " ")
+}
+
+/**
+The event match the draft page was opened from, or nil if it wasn't opened from one.
+*/
+func parseDraftEvent(c *gin.Context) *draftEvent {
+	id := c.Query("event")
+	if id == "" || eventStore == nil {
+		return nil
+	}
+	e, err := eventStore.Load(id)
+	if err != nil {
+		return nil
+	}
+	round, err1 := strconv.Atoi(c.Query("event-round"))
+	pairing, err2 := strconv.Atoi(c.Query("event-pairing"))
+	if err1 != nil || err2 != nil || round < 0 || round >= len(e.Rounds) || pairing < 0 || pairing >= len(e.Rounds[round].Pairings) {
+		return nil
+	}
+	p := e.Rounds[round].Pairings[pairing]
+	return &draftEvent{
+		ID:      e.ID,
+		Name:    e.Name,
+		Round:   round,
+		Pairing: pairing,
+		P1:      p.P1,
+		P2:      p.P2,
+		Winner:  p.Winner,
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createTestEvent(t *testing.T, r *gin.Engine) eventResponse {
+	body, _ := json.Marshal(createEventRequest{Name: "Cup", Format: "round-robin", Players: []string{"A", "B"}, RoundCount: 3})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewReader(body)))
+	var created eventResponse
+	if w.Code != http.StatusCreated || json.Unmarshal(w.Body.Bytes(), &created) != nil {
+		t.Fatalf("Expected an event to be created but got %d %s", w.Code, w.Body.String())
+	}
+	return created
+}
+
+func TestRecordResultIsPosted(t *testing.T) {
+	r := testRouter(t)
+	created := createTestEvent(t, r)
+	record := "/events/" + created.Event.ID + "/record"
+	form := url.Values{
+		"rounds":        {"3"},
+		"picks":         {"SL TZ"},
+		"p1pick":        {"TZ"},
+		"p2pick":        {"GC"},
+		"event-round":   {"0"},
+		"event-pairing": {"0"},
+		"event-winner":  {"P2"},
+	}
+
+	// Following a link, prefetching or reloading mustn't record anything.
+	if w := get(r, record+"?"+form.Encode()); w.Code != http.StatusNotFound {
+		t.Errorf("Expected recording through a GET to be refused but got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, record, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, request)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Expected the result to be recorded but got %d %s", w.Code, w.Body.String())
+	}
+
+	e, err := eventStore.Load(created.Event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairing := e.Rounds[0].Pairings[0]
+	if pairing.Winner != P2 || len(pairing.GameState.P2Rounds) != 1 || pairing.GameState.P2Rounds[0].Matchup.P1 != TZ {
+		t.Errorf("Expected the posted draft and winner to be saved but got %+v", pairing)
+	}
+}
+
+func TestRecordIllegalDraft(t *testing.T) {
+	r := testRouter(t)
+	created := createTestEvent(t, r)
+
+	// P1 plays NG, which wasn't one of their initial picks.
+	body, _ := json.Marshal(recordResultRequest{
+		Round:     0,
+		Pairing:   0,
+		GameState: GameState{P2Rounds: []P2Round{{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: NG, P2: GC}}}},
+		Winner:    P1,
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/events/"+created.Event.ID+"/results", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an illegal draft to be a bad request to the API but got %d %s", w.Code, w.Body.String())
+	}
+
+	// The form's own rules don't count: a Bo5 draft doesn't fit the event's Bo3.
+	form := url.Values{
+		"rounds":        {"5"},
+		"picks":         {"SL TZ", "KH OK", "GC KI"},
+		"p1pick":        {"TZ", "NG", "GC"},
+		"p2pick":        {"GC", "KH", "SL"},
+		"event-round":   {"0"},
+		"event-pairing": {"0"},
+		"event-winner":  {"P1"},
+	}
+	w = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/events/"+created.Event.ID+"/record", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(w, request)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a draft that breaks the event's rules to be a bad request but got %d", w.Code)
+	}
+
+	e, _ := eventStore.Load(created.Event.ID)
+	if pairing := e.Rounds[0].Pairings[0]; pairing.Winner != NoOneYet || len(pairing.GameState.P2Rounds) != 0 {
+		t.Errorf("Expected nothing to be recorded but got %+v", pairing)
+	}
+}
@@ -1,10 +1,12 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	. "github.com/tmwilder/wh3-draftbot/internal/algo"
 	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,99 +15,291 @@ import (
 type pageData struct {
 	GameState            GameState
 	TournamentInfo       TournamentInfo
+	Rulesets             map[string]Ruleset
 	SuggestedLine        GameState
 	WinRate              float64
 	RenderRec            bool
 	RecommendedGameState GameState
+	ScoreOdds            []ScoreOdds
+	Objective            objectiveInputs
+	ObjectiveValue       float64
+	SearchStats          SearchStats
+	SearchSource         string
+	RenderBlind          bool
+	BlindSolution        SimultaneousSolution
+	RenderReview         bool
+	Review               DraftReview
+	MistakeThreshold     float64
+	Event                *draftEvent
+	// Error is why the request couldn't be answered, shown in place of any results.
+	Error string
 }
 
+// objectiveInputs are the form's settings for what P1 drafts for, kept to fill the form back in.
+type objectiveInputs struct {
+	Name        string
+	Points      string
+	RiskPenalty float64
+}
+
+// Win rate a move has to give up to be called a mistake in draft reviews, unless the request says otherwise.
+const defaultMistakeThreshold = .05
+
 func viewHandler(c *gin.Context) {
-	tournamentInfo, gameState, _ := parseInputs(c)
+	tournamentInfo, gameState, _, err := parseInputs(c)
+	_, objectiveInputs, objectiveErr := parseObjective(c)
+	if err = firstError(err, objectiveErr); err != nil {
+		renderInputError(c, tournamentInfo, gameState, err)
+		return
+	}
 	tournamentInfo, gameState = applyDefaults(tournamentInfo, gameState)
 	pageData := pageData{
 		TournamentInfo: tournamentInfo,
+		Rulesets:       Rulesets,
 		WinRate:        0.0,
 		GameState:      gameState,
 		RenderRec:      false,
+		Objective:      objectiveInputs,
+		Event:          parseDraftEvent(c),
 	}
 	c.HTML(http.StatusOK, "draftbot.html", pageData)
 }
 
 func recommendHandler(c *gin.Context) {
-	tournamentInfo, gameState, isP1PickNext := parseInputs(c)
-	winRate, recommendedGameState := TurinMinimax(tournamentInfo, gameState, isP1PickNext, -1.0, 2.0)
+	tournamentInfo, gameState, isP1PickNext, err := parseInputs(c)
+	objective, objectiveInputs, objectiveErr := parseObjective(c)
+	if err = firstError(err, objectiveErr); err != nil {
+		renderInputError(c, tournamentInfo, gameState, err)
+		return
+	}
+	result := recommend(requestLogger(c), tournamentInfo, gameState, isP1PickNext, objective, objectiveInputs, nil)
 	paddedTournamentInfo, paddedGameState := applyDefaults(tournamentInfo, gameState)
 
 	c.HTML(http.StatusOK, "draftbot.html", pageData{
 		GameState:            paddedGameState,
 		TournamentInfo:       paddedTournamentInfo,
-		WinRate:              winRate,
-		RecommendedGameState: recommendedGameState,
+		Rulesets:             Rulesets,
+		WinRate:              result.WinRate,
+		RecommendedGameState: result.Line,
+		ScoreOdds:            result.ScoreOdds,
+		Objective:            objectiveInputs,
+		ObjectiveValue:       result.ObjectiveValue,
+		SearchStats:          result.Stats,
+		SearchSource:         result.Source,
 		RenderRec:            true,
+		RenderBlind:          result.IsBlind,
+		BlindSolution:        result.BlindSolution,
+		Event:                parseDraftEvent(c),
+	})
+}
+
+func reviewHandler(c *gin.Context) {
+	tournamentInfo, gameState, _, err := parseInputs(c)
+	_, objectiveInputs, objectiveErr := parseObjective(c)
+	if err = firstError(err, objectiveErr); err != nil {
+		renderInputError(c, tournamentInfo, gameState, err)
+		return
+	}
+	threshold, err := parseThreshold(c.Query("threshold"))
+	if err != nil {
+		renderInputError(c, tournamentInfo, gameState, &inputError{Param: "threshold", Err: err})
+		return
+	}
+	draftReview := review(requestLogger(c), tournamentInfo, gameState, threshold)
+	paddedTournamentInfo, paddedGameState := applyDefaults(tournamentInfo, gameState)
+
+	c.HTML(http.StatusOK, "draftbot.html", pageData{
+		GameState:        paddedGameState,
+		TournamentInfo:   paddedTournamentInfo,
+		Rulesets:         Rulesets,
+		RenderReview:     true,
+		Review:           draftReview,
+		MistakeThreshold: threshold,
+		Objective:        objectiveInputs,
+		Event:            parseDraftEvent(c),
 	})
 }
 
 /**
-Nothing to see here.
+Shows the draft page filled back in with what could be read of the request, and err where the results would be.
 */
-func parseInputs(c *gin.Context) (TournamentInfo, GameState, bool) {
-	queryParams := c.Request.URL.Query()
+func renderInputError(c *gin.Context, tournamentInfo TournamentInfo, gameState GameState, err error) {
+	inputWarning(c, err)
+	_, objectiveInputs, _ := parseObjective(c)
+	tournamentInfo, gameState = applyDefaults(tournamentInfo, gameState)
+	c.HTML(http.StatusBadRequest, "draftbot.html", pageData{
+		GameState:      gameState,
+		TournamentInfo: tournamentInfo,
+		Rulesets:       Rulesets,
+		Objective:      objectiveInputs,
+		Event:          parseDraftEvent(c),
+		Error:          err.Error(),
+	})
+}
+
+/**
+Reads the draft from the query. Input that can't be used is reported as an *inputError, or a draft that breaks the
+rules as an *IllegalDraftError, along with as much of the draft as could be read so that the form can be filled back in.
+*/
+func parseInputs(c *gin.Context) (TournamentInfo, GameState, bool, error) {
+	var inputErr error
+	// The draft is in the query, or in the form when it is posted to be recorded.
+	if err := c.Request.ParseForm(); err != nil {
+		inputErr = &inputError{Param: "the query", Err: err}
+	}
+	queryParams := c.Request.Form
 	// Extract matchup odds
 	// We'll do it the gross way so we can remember life without tools ;)
 	matchupOdds := map[Matchup]float64{}
 	for k, v := range queryParams {
-		if k[0:5] == "odds-" {
-			f1 := Faction(k[5:7])
-			f2 := Faction(k[7:])
-			odds, err := strconv.ParseFloat(v[0], 64)
+		if strings.HasPrefix(k, "odds-") {
+			var matchup Matchup
+			if err := matchup.UnmarshalText([]byte(k[5:])); err != nil {
+				// Older links write race matchups without a dash, e.g. odds-GCKH.
+				if len(k) < 9 {
+					inputErr = firstError(inputErr, &inputError{Param: k, Err: fmt.Errorf("%q isn't a matchup", k[5:])})
+					continue
+				}
+				matchup = Matchup{P1: Faction(k[5:7]), P2: Faction(k[7:])}
+			}
+			odds, err := parseOdds(v[0])
 			if err != nil {
-				panic("Cannot parse input: " + err.Error())
+				inputErr = firstError(inputErr, &inputError{Param: k, Err: err})
+				continue
 			}
-			matchupOdds[Matchup{P1: f1, P2: f2}] = odds
+			matchupOdds[matchup] = odds
+		}
+	}
+	// More odds can be added a line at a time as P1-P2=odds or P1-P2@Map=odds.
+	for _, line := range strings.Split(queryParams.Get("extra-odds"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		var matchup Matchup
+		if !found || matchup.UnmarshalText([]byte(strings.TrimSpace(key))) != nil {
+			err := fmt.Errorf("%q isn't a matchup and odds, e.g. GC-KH=0.55", strings.TrimSpace(line))
+			inputErr = firstError(inputErr, &inputError{Param: "extra-odds", Err: err})
+			continue
+		}
+		odds, err := parseOdds(strings.TrimSpace(value))
+		if err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "extra-odds", Err: fmt.Errorf("%s: %w", strings.TrimSpace(key), err)})
+			continue
 		}
+		matchupOdds[matchup] = odds
 	}
 
-	for k, v := range MatchupsV1d2 {
+	for k, v := range defaultMatchupOdds {
 		if _, ok := matchupOdds[k]; !ok {
 			matchupOdds[k] = v
 		}
 	}
 
-	roundsStr := queryParams.Get("rounds")
-	roundCount, err := strconv.ParseInt(roundsStr, 0, 64)
-	if err != nil {
-		fmt.Println("Cannot parse input: " + err.Error())
-		roundCount = 3
+	// A page opened without a draft starts as a Bo3, but a round count that was sent has to be one that can be drafted.
+	roundCount := 3
+	if queryParams.Has("rounds") {
+		parsed, err := parseRoundCount(queryParams.Get("rounds"))
+		if err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "rounds", Err: err})
+		} else {
+			roundCount = parsed
+		}
+	}
+	ruleset, ok := Rulesets[queryParams.Get("ruleset")]
+	if !ok {
+		ruleset = defaultRuleset
+	}
+	if factionPool := parsePicks(queryParams.Get("faction-pool")); len(factionPool) > 0 {
+		ruleset.FactionPool = factionPool
+	}
+	if repeats := queryParams.Get("repeats"); repeats != "" {
+		if err := checkChoice(repeats, RaceRepeats, LordRepeats, AllowRepeats); err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "repeats", Err: err})
+		} else {
+			ruleset.Repeats = RepeatGranularity(repeats)
+		}
 	}
-	tournamentInfo := TournamentInfo{RoundCount: int(roundCount), MatchupOdds: matchupOdds}
+	// The form leaves the ruleset's own global bans in place by sending nothing.
+	if globalBansStr := queryParams.Get("global-bans-per-player"); globalBansStr != "" {
+		globalBans, err := parseBanCount(globalBansStr)
+		if err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "global-bans-per-player", Err: err})
+		} else {
+			ruleset.GlobalBansPerPlayer = globalBans
+		}
+	}
+	if firstPick := queryParams.Get("first-pick"); firstPick != "" {
+		if err := checkChoice(firstPick, AlternateFirstPick, LoserPicksFirst, WinnerPicksFirst); err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "first-pick", Err: err})
+		} else {
+			ruleset.FirstPick = FirstPickRule(firstPick)
+		}
+	}
+	if lockedOut, err := strconv.ParseBool(queryParams.Get("winner-locked-out")); err == nil {
+		ruleset.WinnerLockedOut = lockedOut
+	}
+	if stopWhenDecided, err := strconv.ParseBool(queryParams.Get("stop-when-decided")); err == nil {
+		ruleset.StopWhenDecided = stopWhenDecided
+	}
+	ruleset.MapPool = parseMaps(queryParams.Get("map-pool"))
+	if mapBansStr := queryParams.Get("map-bans-per-player"); mapBansStr != "" {
+		mapBans, err := parseBanCount(mapBansStr)
+		// Both players' bans have to leave at least one map to play on.
+		if err == nil && len(ruleset.MapPool) > 0 && 2*mapBans >= len(ruleset.MapPool) {
+			err = fmt.Errorf("a pool of %d maps leaves nothing to play after %d bans each", len(ruleset.MapPool), mapBans)
+		}
+		if err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "map-bans-per-player", Err: err})
+		} else {
+			ruleset.MapBansPerPlayer = mapBans
+		}
+	}
+	if mapPicker := queryParams.Get("map-picker"); mapPicker != "" {
+		if err := checkChoice(mapPicker, FirstPickerPicksMap, CounterPickerPicksMap); err != nil {
+			inputErr = firstError(inputErr, &inputError{Param: "map-picker", Err: err})
+		} else {
+			ruleset.MapPicker = MapPicker(mapPicker)
+		}
+	}
+	tournamentInfo := TournamentInfo{RoundCount: roundCount, MatchupOdds: matchupOdds, Ruleset: ruleset}
 
-	picks := c.QueryArray("picks")
-	p1picks := c.QueryArray("p1pick")
-	p2picks := c.QueryArray("p2pick")
-	whowon := c.QueryArray("whowon")
+	picks := queryParams["picks"]
+	p1picks := queryParams["p1pick"]
+	p2picks := queryParams["p2pick"]
+	whowon := queryParams["whowon"]
+	maps := queryParams["map"]
 
 	// Populate the P2Round info
 	var p2Rounds []P2Round
 	for i, v := range picks {
-		if v == "" {
+		if v == "" && (i >= len(maps) || maps[i] == "") {
 			continue
 		}
-		// TODO figure out validation and user input story
 		roundPicks := parsePicks(v)
 
 		matchup := Matchup{}
-		if len(p1picks) >= i {
+		if i < len(p1picks) {
 			matchup.P1 = Faction(p1picks[i])
 		}
 
-		if len(p2picks) >= i {
+		if i < len(p2picks) {
 			matchup.P2 = Faction(p2picks[i])
 		}
 
+		if i < len(maps) {
+			matchup.Map = GameMap(maps[i])
+		}
+
 		var whoWonThisRound WhoWon
-		if len(whowon) >= i {
+		if i < len(whowon) {
 			whoWonThisRound = WhoWon(whowon[i])
 		}
+		if whoWonThisRound != NoOneYet && whoWonThisRound != P1 && whoWonThisRound != P2 {
+			err := fmt.Errorf("game %d was won by P1 or P2, or no one yet, but got %q", i+1, whoWonThisRound)
+			inputErr = firstError(inputErr, &inputError{Param: "whowon", Err: err})
+			whoWonThisRound = NoOneYet
+		}
 		round := P2Round{Picks: roundPicks, Matchup: matchup, WhoWon: whoWonThisRound}
 		p2Rounds = append(p2Rounds, round)
 	}
@@ -113,20 +307,232 @@ func parseInputs(c *gin.Context) (TournamentInfo, GameState, bool) {
 	// Populate P3Round
 	p3Round := P3Round{}
 
-	p3Round.Picks = parsePicks(c.Query("last-picks"))
-	p3Round.Ban = Faction(c.Query("last-ban"))
-	p3Round.CounterBan = Faction(c.Query("last-counter-ban"))
-	p3Round.Matchup.P1 = Faction(c.Query("last-p1pick"))
-	p3Round.Matchup.P2 = Faction(c.Query("last-p2pick"))
+	p3Round.Picks = parsePicks(queryParams.Get("last-picks"))
+	p3Round.Ban = Faction(queryParams.Get("last-ban"))
+	p3Round.CounterBan = Faction(queryParams.Get("last-counter-ban"))
+	p3Round.Matchup.P1 = Faction(queryParams.Get("last-p1pick"))
+	p3Round.Matchup.P2 = Faction(queryParams.Get("last-p2pick"))
+	p3Round.Matchup.Map = GameMap(queryParams.Get("last-map"))
 
 	gameState := GameState{
 		P2Rounds: p2Rounds,
 		P3Round:  p3Round,
+		MapBans:  parseMaps(queryParams.Get("banned-maps")),
+		P1Bans:   parsePicks(queryParams.Get("p1-bans")),
+		P2Bans:   parsePicks(queryParams.Get("p2-bans")),
+	}
+
+	if inputErr == nil {
+		inputErr = tournamentInfo.Validate()
+	}
+	if inputErr == nil {
+		inputErr = tournamentInfo.ValidateOdds()
+	}
+	if inputErr == nil {
+		inputErr = ValidateGameState(tournamentInfo, gameState)
+	}
+	if inputErr != nil {
+		return tournamentInfo, gameState, false, inputErr
 	}
 
 	isP1PickNext := IsP1PickNext(tournamentInfo, gameState)
 
-	return tournamentInfo, gameState, isP1PickNext
+	return tournamentInfo, gameState, isP1PickNext, nil
+}
+
+/**
+Checks that value is one of choices, which are any of the rule enums.
+*/
+func checkChoice(value string, choices ...interface{}) error {
+	var names []string
+	for _, v := range choices {
+		if value == fmt.Sprint(v) {
+			return nil
+		}
+		names = append(names, fmt.Sprintf("%q", v))
+	}
+	return fmt.Errorf("%q isn't one of %s", value, strings.Join(names, ", "))
+}
+
+func parseBanCount(bansStr string) (int, error) {
+	bans, err := strconv.Atoi(bansStr)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", bansStr)
+	}
+	if bans < 0 {
+		return 0, fmt.Errorf("bans per player can't be negative but got %d", bans)
+	}
+	return bans, nil
+}
+
+func parseOdds(oddsStr string) (float64, error) {
+	odds, err := strconv.ParseFloat(oddsStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", oddsStr)
+	}
+	if !(odds >= 0 && odds <= 1) {
+		return 0, fmt.Errorf("odds should be between 0 and 1 but got %v", odds)
+	}
+	return odds, nil
+}
+
+/**
+The win rate a move has to give up to be called a mistake, defaultMistakeThreshold if none is given.
+*/
+func parseThreshold(thresholdStr string) (float64, error) {
+	if thresholdStr == "" {
+		return defaultMistakeThreshold, nil
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", thresholdStr)
+	}
+	if !(threshold >= 0 && threshold <= 1) {
+		return 0, fmt.Errorf("the threshold should be between 0 and 1 but got %v", threshold)
+	}
+	return threshold, nil
+}
+
+func parseRoundCount(roundsStr string) (int, error) {
+	roundCount, err := strconv.Atoi(roundsStr)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", roundsStr)
+	}
+	if err := ValidateRoundCount(roundCount); err != nil {
+		return 0, err
+	}
+	if roundCount > maxRoundCount {
+		return 0, fmt.Errorf("this server drafts at most %d rounds but got %d", maxRoundCount, roundCount)
+	}
+	return roundCount, nil
+}
+
+func firstError(err error, next error) error {
+	if err != nil {
+		return err
+	}
+	return next
+}
+
+/**
+What P1 drafts for, nil for the series win rate. A positive risk penalty makes the objective risk averse. A points
+table that can't be read is an *inputError, returned along with the inputs so that the form can be filled back in.
+*/
+func parseObjective(c *gin.Context) (Objective, objectiveInputs, error) {
+	inputs := objectiveInputs{Name: c.Query("objective"), Points: c.Query("points")}
+	var score ScoreValue
+	switch inputs.Name {
+	case "game-wins":
+		score = GameWins
+	case "league-points":
+		score = LeaguePoints(3, 1)
+	case "points":
+		points, err := parsePoints(inputs.Points)
+		if err != nil {
+			return nil, inputs, &inputError{Param: "points", Err: err}
+		}
+		score = PointsTable(points)
+	case "one-game":
+		score = AtLeastOneGame
+	default:
+		inputs.Name = ""
+	}
+
+	penalty, err := parseRiskPenalty(c.Query("risk-penalty"))
+	if err != nil {
+		return nil, inputs, &inputError{Param: "risk-penalty", Err: err}
+	}
+	if penalty > 0 {
+		inputs.RiskPenalty = penalty
+		if score == nil {
+			score = SeriesWin
+		}
+		return VariancePenalty{Score: score, Penalty: penalty}, inputs, nil
+	}
+	if score == nil {
+		return nil, inputs, nil
+	}
+	return score, inputs, nil
+}
+
+/**
+The scouted opponent to play against, nil to assume perfect play. opponent is the seat it sits in, P1 or P2.
+opponent-picks weights the factions it likes to play, comma separated as faction=weight, e.g. TZ=10, KI:KAT=4, and
+opponent-blend is how far to trust that, from 0 for not at all to 1, the default, for completely.
+*/
+func parseOpponent(c *gin.Context) (*opponentInputs, error) {
+	seat := WhoWon(c.Query("opponent"))
+	if seat == "" {
+		return nil, nil
+	}
+	if seat != P1 && seat != P2 {
+		return nil, &inputError{Param: "opponent", Err: fmt.Errorf("the opponent sits as P1 or P2 but got %q", seat)}
+	}
+	opponent := opponentInputs{Model: OpponentModel{IsP1: seat == P1, ComfortPicks: map[Faction]float64{}}, Blend: 1.0}
+	for _, v := range strings.Split(c.Query("opponent-picks"), ",") {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		faction, weightStr, found := strings.Cut(strings.TrimSpace(v), "=")
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if !found || err != nil || weight < 0 {
+			return nil, &inputError{Param: "opponent-picks", Err: fmt.Errorf("%q isn't a faction and a weight, e.g. TZ=10", strings.TrimSpace(v))}
+		}
+		opponent.Model.ComfortPicks[Faction(strings.TrimSpace(faction))] = weight
+	}
+	if blendStr := c.Query("opponent-blend"); blendStr != "" {
+		blend, err := strconv.ParseFloat(blendStr, 64)
+		if err != nil || !(blend >= 0 && blend <= 1) {
+			return nil, &inputError{Param: "opponent-blend", Err: fmt.Errorf("the blend should be between 0 and 1 but got %q", blendStr)}
+		}
+		opponent.Blend = blend
+	}
+	return &opponent, nil
+}
+
+/**
+Reads a risk penalty, which is none if it's left empty. It has to be a finite number that isn't negative.
+*/
+func parseRiskPenalty(penaltyStr string) (float64, error) {
+	if penaltyStr == "" {
+		return 0, nil
+	}
+	penalty, err := strconv.ParseFloat(penaltyStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", penaltyStr)
+	}
+	if math.IsInf(penalty, 0) || math.IsNaN(penalty) {
+		return 0, fmt.Errorf("%q isn't a finite number", penaltyStr)
+	}
+	if penalty < 0 {
+		return 0, fmt.Errorf("a risk penalty can't be negative but got %v", penalty)
+	}
+	return penalty, nil
+}
+
+/**
+Points tables are comma separated, as score=points, e.g. 2-0=3, 2-1=2, 1-2=1.
+*/
+func parsePoints(pointsStr string) (map[Score]float64, error) {
+	points := map[Score]float64{}
+	for _, v := range strings.Split(pointsStr, ",") {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimSpace(v), "=")
+		p1Wins, p2Wins, _ := strings.Cut(strings.TrimSpace(key), "-")
+		p1, p1Err := strconv.Atoi(p1Wins)
+		p2, p2Err := strconv.Atoi(p2Wins)
+		scorePoints, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !found || p1Err != nil || p2Err != nil || err != nil {
+			return nil, fmt.Errorf("%q isn't a score and points, e.g. 2-0=3", strings.TrimSpace(v))
+		}
+		points[Score{P1: p1, P2: p2}] = scorePoints
+	}
+	if len(points) == 0 {
+		return nil, errors.New("a points table needs points for at least one score, e.g. 2-0=3")
+	}
+	return points, nil
 }
 
 func parsePicks(factionStr string) []Faction {
@@ -142,6 +548,38 @@ func parsePicks(factionStr string) []Faction {
 	}
 }
 
+/**
+Map names can have spaces in them, so lists of maps are comma separated.
+*/
+func parseMaps(mapStr string) []GameMap {
+	var maps []GameMap
+	for _, v := range strings.Split(mapStr, ",") {
+		if gameMap := strings.TrimSpace(v); gameMap != "" {
+			maps = append(maps, GameMap(gameMap))
+		}
+	}
+	return maps
+}
+
+/**
+The faction pool ruleset drafts from, if it isn't the pool its named ruleset comes with, for the form to send back.
+*/
+func factionPoolOverride(ruleset Ruleset) string {
+	pool := joinPicks(ruleset.FactionPool)
+	if pool == joinPicks(Rulesets[ruleset.Name].FactionPool) {
+		return ""
+	}
+	return pool
+}
+
+func joinMaps(maps []GameMap) string {
+	var names []string
+	for _, v := range maps {
+		names = append(names, string(v))
+	}
+	return strings.Join(names, ", ")
+}
+
 func applyDefaults(info TournamentInfo, state GameState) (TournamentInfo, GameState) {
 	// Pad the p2rounds out to full event
 	p2PickDeficit := info.RoundCount - len(state.P2Rounds) - 1
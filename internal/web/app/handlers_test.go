@@ -0,0 +1,285 @@
+package app
+
+import (
+	"errors"
+	"flag"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/algo"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const epsilon = .0001
+
+// A Bo3 one game in, as the draft page submits it: every field is sent, filled in or not.
+const partialDraft = "rounds=3&ruleset=2022-Q2-Turin-Default&faction-pool=&repeats=&first-pick=&winner-locked-out=" +
+	"&stop-when-decided=&global-bans-per-player=&map-pool=&map-bans-per-player=0&map-picker=first-picker" +
+	"&picks=SL+TZ&p2pick=GC&p1pick=TZ&whowon=P2" +
+	"&picks=KH+TZ&p2pick=&p1pick=&whowon=" +
+	"&last-picks=&last-ban=&last-counter-ban=&last-p2pick=&last-p1pick=" +
+	"&objective=&points=&risk-penalty=&threshold=" +
+	"&odds-GC-KH=0.7&extra-odds=SL-TZ%3D0.35%0AGC-OK%3D0.45"
+
+func parseQuery(query string) (TournamentInfo, GameState, bool, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/view?"+query, nil)
+	return parseInputs(c)
+}
+
+func TestParseInputs(t *testing.T) {
+	testRouter(t)
+
+	tournamentInfo, gameState, isP1PickNext, err := parseQuery(partialDraft)
+	if err != nil {
+		t.Fatalf("Expected the draft to be read but got %s", err)
+	}
+	if tournamentInfo.RoundCount != 3 || tournamentInfo.Ruleset.Name != Turin2022Q2.Name {
+		t.Errorf("Expected a Bo3 under %s but got a Bo%d under %s", Turin2022Q2.Name, tournamentInfo.RoundCount, tournamentInfo.Ruleset.Name)
+	}
+	custom := map[Matchup]float64{
+		{P1: GC, P2: KH}: .7,
+		{P1: SL, P2: TZ}: .35,
+		{P1: GC, P2: OK}: .45,
+	}
+	for matchup, expected := range custom {
+		if odds := tournamentInfo.MatchupOdds[matchup]; odds < expected-epsilon || odds > expected+epsilon {
+			t.Errorf("Expected %s-%s to be %v but got %v", matchup.P1, matchup.P2, expected, odds)
+		}
+	}
+	for k, v := range defaultMatchupOdds {
+		if _, ok := tournamentInfo.MatchupOdds[k]; !ok {
+			t.Errorf("Expected the default odds for %s-%s to be kept but got none", k.P1, k.P2)
+		} else if _, ok := custom[k]; !ok && tournamentInfo.MatchupOdds[k] != v {
+			t.Errorf("Expected the default odds for %s-%s but got %v", k.P1, k.P2, tournamentInfo.MatchupOdds[k])
+		}
+	}
+
+	expected := []P2Round{
+		{Picks: []Faction{SL, TZ}, Matchup: Matchup{P1: TZ, P2: GC}, WhoWon: P2},
+		{Picks: []Faction{KH, TZ}},
+	}
+	if !reflect.DeepEqual(gameState.P2Rounds, expected) {
+		t.Errorf("Expected rounds %+v but got %+v", expected, gameState.P2Rounds)
+	}
+	if len(gameState.P3Round.Picks) != 0 || gameState.P3Round.Matchup != (Matchup{}) {
+		t.Errorf("Expected the final round to be empty but got %+v", gameState.P3Round)
+	}
+	if isP1PickNext != IsP1PickNext(tournamentInfo, gameState) {
+		t.Errorf("Expected isP1PickNext to be %v", !isP1PickNext)
+	}
+}
+
+func TestParseInputsRulesetOverrides(t *testing.T) {
+	testRouter(t)
+
+	tournamentInfo, _, _, err := parseQuery("rounds=3&ruleset=2022-Q2-Turin-Default&faction-pool=GC+KH+OK+SL+TZ+KI+NG" +
+		"&repeats=allowed&first-pick=loser&winner-locked-out=true&stop-when-decided=true&global-bans-per-player=1" +
+		"&map-pool=Black+Fortress%2C+Karak+Ungor&map-bans-per-player=0&map-picker=counter-picker")
+	if err != nil {
+		t.Fatalf("Expected the rules to be read but got %s", err)
+	}
+	ruleset := tournamentInfo.Ruleset
+	if !reflect.DeepEqual(ruleset.FactionPool, []Faction{GC, KH, OK, SL, TZ, KI, NG}) {
+		t.Errorf("Expected the faction pool to be read but got %v", ruleset.FactionPool)
+	}
+	if ruleset.Repeats != AllowRepeats || ruleset.FirstPick != LoserPicksFirst || !ruleset.WinnerLockedOut || !ruleset.StopWhenDecided {
+		t.Errorf("Expected the repeat, first pick and winner rules to be read but got %+v", ruleset)
+	}
+	if ruleset.GlobalBansPerPlayer != 1 {
+		t.Errorf("Expected 1 global ban per player but got %d", ruleset.GlobalBansPerPlayer)
+	}
+	if !reflect.DeepEqual(ruleset.MapPool, []GameMap{"Black Fortress", "Karak Ungor"}) || ruleset.MapPicker != CounterPickerPicksMap {
+		t.Errorf("Expected the map pool and picker to be read but got %v and %s", ruleset.MapPool, ruleset.MapPicker)
+	}
+}
+
+func TestParseInputsFallsBack(t *testing.T) {
+	testRouter(t)
+
+	tournamentInfo, _, _, err := parseQuery("rounds=3&ruleset=nope")
+	if err != nil || tournamentInfo.Ruleset.Name != defaultRuleset.Name {
+		t.Errorf("Expected an unknown ruleset to fall back to %s but got %s, %v", defaultRuleset.Name, tournamentInfo.Ruleset.Name, err)
+	}
+	tournamentInfo, _, _, err = parseQuery("")
+	if err != nil || tournamentInfo.RoundCount != 3 {
+		t.Errorf("Expected a page without a round count to start as a Bo3 but got a Bo%d, %v", tournamentInfo.RoundCount, err)
+	}
+
+	// A round count that was sent is never quietly swapped for another.
+	for name, query := range map[string]string{
+		"an even round count":     "rounds=4",
+		"a round count over 9":    "rounds=11",
+		"a round count not given": "rounds=",
+	} {
+		var inputErr *inputError
+		if _, _, _, err := parseQuery(query); !errors.As(err, &inputErr) || inputErr.Param != "rounds" {
+			t.Errorf("Expected %s to be an error reading rounds but got %v", name, err)
+		}
+	}
+
+	// Older links write race matchups without a dash.
+	tournamentInfo, _, _, err = parseQuery("rounds=3&odds-GCKH=0.6")
+	if err != nil || tournamentInfo.MatchupOdds[Matchup{P1: GC, P2: KH}] != .6 {
+		t.Errorf("Expected odds-GCKH to be read as GC-KH but got %v, %v", tournamentInfo.MatchupOdds[Matchup{P1: GC, P2: KH}], err)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	tournamentInfo := TournamentInfo{RoundCount: 5}
+	oneRound := []P2Round{{Picks: []Faction{SL, TZ}}}
+
+	_, gameState := applyDefaults(tournamentInfo, GameState{P2Rounds: oneRound})
+	if len(gameState.P2Rounds) != 4 || !reflect.DeepEqual(gameState.P2Rounds[0], oneRound[0]) {
+		t.Errorf("Expected a Bo5 to be padded out to 4 rounds after the one played but got %+v", gameState.P2Rounds)
+	}
+	for _, v := range gameState.P2Rounds[1:] {
+		if !reflect.DeepEqual(v, P2Round{}) {
+			t.Errorf("Expected padding to be empty rounds but got %+v", v)
+		}
+	}
+
+	fourRounds := make([]P2Round, 4)
+	_, gameState = applyDefaults(TournamentInfo{RoundCount: 3}, GameState{P2Rounds: fourRounds})
+	if len(gameState.P2Rounds) != 4 {
+		t.Errorf("Expected rounds already there to be kept but got %d", len(gameState.P2Rounds))
+	}
+}
+
+var (
+	inputPattern    = regexp.MustCompile(`<input [^>]*name="([^"]*)"[^>]*value="([^"]*)"`)
+	selectPattern   = regexp.MustCompile(`(?s)<select [^>]*name="([^"]*)"[^>]*>(.*?)</select>`)
+	selectedPattern = regexp.MustCompile(`<option value="([^"]*)"[^>]*selected`)
+)
+
+/**
+The query the draft page's form would submit as rendered, in the order the fields appear.
+*/
+func formQuery(page string) string {
+	var fields []string
+	add := func(name string, value string) {
+		fields = append(fields, url.QueryEscape(html.UnescapeString(name))+"="+url.QueryEscape(html.UnescapeString(value)))
+	}
+	type field struct {
+		at    int
+		name  string
+		value string
+	}
+	var found []field
+	for _, v := range inputPattern.FindAllStringSubmatchIndex(page, -1) {
+		found = append(found, field{v[0], page[v[2]:v[3]], page[v[4]:v[5]]})
+	}
+	for _, v := range selectPattern.FindAllStringSubmatchIndex(page, -1) {
+		value := ""
+		if selected := selectedPattern.FindStringSubmatch(page[v[4]:v[5]]); selected != nil {
+			value = selected[1]
+		}
+		found = append(found, field{v[0], page[v[2]:v[3]], value})
+	}
+	// Fields of a round are read by position, so they have to go back in the order the page has them.
+	for i := range found {
+		for j := i + 1; j < len(found); j++ {
+			if found[j].at < found[i].at {
+				found[i], found[j] = found[j], found[i]
+			}
+		}
+	}
+	for _, v := range found {
+		add(v.name, v.value)
+	}
+	return strings.Join(fields, "&")
+}
+
+func TestFormRoundTrip(t *testing.T) {
+	r := testRouter(t)
+
+	for name, query := range map[string]string{
+		"an empty Bo3":                      "rounds=3",
+		"a partial Bo3":                     partialDraft,
+		"a Bo5 two games in":                "rounds=5&picks=SL+TZ&p1pick=TZ&p2pick=GC&whowon=P2&picks=KH+OK&p1pick=OK&p2pick=KH&whowon=P1",
+		"a Bo3 into the final":              "rounds=3&picks=SL+TZ&p1pick=TZ&p2pick=GC&picks=KH+OK&p1pick=OK&p2pick=KH&last-picks=GC+KI+NG&last-ban=KI",
+		"a blank round before a played one": "rounds=3&picks=&p1pick=&p2pick=&picks=SL+TZ&p1pick=TZ&p2pick=GC",
+		"rules other than the ruleset's": "rounds=3&faction-pool=GC+KH+KI+OK+SL+TZ&repeats=lord&global-bans-per-player=1" +
+			"&winner-locked-out=true&first-pick=loser&stop-when-decided=true&p1-bans=KI&p2-bans=OK&picks=SL+TZ&p1pick=TZ&p2pick=GC",
+		"a map pool":                        "rounds=3&map-pool=Black+Fortress%2C+Karak+Ungor&map-bans-per-player=0&map-picker=counter-picker",
+	} {
+		first := get(r, "/view?"+query)
+		if first.Code != http.StatusOK {
+			t.Errorf("Expected %s to render but got %d: %s", name, first.Code, first.Body.String())
+			continue
+		}
+		resubmitted := formQuery(first.Body.String())
+		tournamentInfo, gameState, _, err := parseQuery(query)
+		resubmittedInfo, resubmittedState, _, resubmittedErr := parseQuery(resubmitted)
+		if err != nil || resubmittedErr != nil {
+			t.Errorf("Expected %s to be read both times but got %v and %v", name, err, resubmittedErr)
+			continue
+		}
+		if !reflect.DeepEqual(resubmittedState, gameState) {
+			t.Errorf("Expected %s to come back from the form as\n%+v\nbut got\n%+v", name, gameState, resubmittedState)
+		}
+		if !reflect.DeepEqual(resubmittedInfo, tournamentInfo) {
+			t.Errorf("Expected %s to keep its rules and odds through the form", name)
+		}
+		if second := get(r, "/view?"+resubmitted); second.Body.String() != first.Body.String() {
+			t.Errorf("Expected %s to render the same after being resubmitted", name)
+		}
+	}
+}
+
+// How long a search took is the one thing on the page that changes from run to run.
+var elapsedPattern = regexp.MustCompile(`positions in [^:]*:`)
+
+/**
+Compares page against testdata/name, or rewrites it with -update.
+*/
+func checkGolden(t *testing.T, name string, page string) {
+	page = elapsedPattern.ReplaceAllString(page, "positions in 0s:")
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Could not read %s, run with -update to write it: %s", path, err)
+	}
+	if page != string(expected) {
+		t.Errorf("Expected the page to match %s, run with -update and diff it to see what changed", path)
+	}
+}
+
+func TestGoldenPages(t *testing.T) {
+	r := testRouter(t)
+
+	for _, test := range []struct {
+		golden string
+		target string
+		status int
+	}{
+		{"view_default.html", "/view", http.StatusOK},
+		{"view_partial.html", "/view?" + partialDraft, http.StatusOK},
+		{"view_final_round.html", "/view?rounds=3&picks=SL+TZ&p1pick=TZ&p2pick=GC&picks=KH+OK&p1pick=OK&p2pick=KH&last-picks=GC+KI+NG&last-ban=KI", http.StatusOK},
+		{"view_bad_odds.html", "/view?" + partialDraft + "&odds-OK-SL=lots", http.StatusBadRequest},
+		{"recommend_partial.html", "/recommend/?" + partialDraft, http.StatusOK},
+		{"recommend_objective.html", "/recommend/?" + strings.Replace(partialDraft, "objective=&points=&risk-penalty=", "objective=game-wins&points=&risk-penalty=0.5", 1), http.StatusOK},
+	} {
+		w := get(r, test.target)
+		if w.Code != test.status {
+			t.Errorf("Expected %s to be a %d but got %d", test.golden, test.status, w.Code)
+		}
+		checkGolden(t, test.golden, w.Body.String())
+	}
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	. "github.com/tmwilder/wh3-draftbot/internal/algo"
+	"github.com/tmwilder/wh3-draftbot/internal/cache"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"github.com/tmwilder/wh3-draftbot/internal/logging"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Search results, shared by the pages and the JSON API. Set up when the app starts.
+var searchCache *cache.Cache
+
+// Positions solved ahead of time, loaded when the app starts.
+var openingBooks []OpeningBook
+
+/**
+recommendation is everything the bot works out for a position: the best line for both players, what it is worth and
+how the series is likely to end, and the equilibrium if the next step is blind.
+*/
+type recommendation struct {
+	WinRate        float64
+	ObjectiveValue float64
+	Line           GameState
+	ScoreOdds      []ScoreOdds
+	IsBlind        bool
+	BlindSolution  SimultaneousSolution
+	// Stats is the work that went into the result when it was searched, however this copy of it was found.
+	Stats SearchStats
+	// Source is where this copy came from: search, cache or book.
+	Source string
+}
+
+// opponentInputs are the settings for playing against a scouted opponent rather than a perfect one.
+type opponentInputs struct {
+	Model OpponentModel
+	Blend float64
+}
+
+/**
+Searches gameState for objective, or takes the result from the cache if the same search has been done before or from
+an opening book that has the position. With an opponent, the series win rate is searched against its model instead.
+Logs to log what was searched, where the result came from and what it took.
+*/
+func recommend(log *logging.Logger, tournamentInfo TournamentInfo, gameState GameState, isP1PickNext bool, objective Objective, inputs objectiveInputs, opponent *opponentInputs) recommendation {
+	start := time.Now()
+	log = log.With(
+		"rounds", tournamentInfo.RoundCount,
+		"ruleset", tournamentInfo.Ruleset.Name,
+		"objective", inputs.Name,
+		"points", inputs.Points,
+		"riskPenalty", inputs.RiskPenalty,
+		"budget", searchBudget,
+	)
+
+	search := fmt.Sprintf("recommend %+v budget %s", inputs, searchBudget)
+	if opponent != nil {
+		search += fmt.Sprintf(" opponent %+v", *opponent)
+		log = log.With("opponentIsP1", opponent.Model.IsP1, "blend", opponent.Blend)
+	}
+	key, err := cache.Key(tournamentInfo, gameState, search)
+	var result recommendation
+	if err == nil && searchCache.Get(key, &result) {
+		result.Source = "cache"
+		logSearch(log, "recommend", result.Source, time.Since(start))
+		return result
+	}
+
+	if opponent != nil {
+		var stats, blindStats SearchStats
+		result.WinRate, result.Line, stats = TurinExploitTimed(tournamentInfo, gameState, isP1PickNext, opponent.Model, opponent.Blend, searchBudget, PoolEvaluator)
+		if IsDraftComplete(tournamentInfo, result.Line) {
+			result.ScoreOdds = ScoreDistribution(tournamentInfo, result.Line)
+		}
+		result.BlindSolution, result.IsBlind, blindStats = SearchSimultaneousStep(tournamentInfo, gameState, SearchOptions{})
+		result.Stats, result.Source = stats.Add(blindStats), "search"
+		logSearch(logStats(log, result.Stats), "recommend", result.Source, time.Since(start))
+		if err == nil {
+			if err := searchCache.Put(key, result); err != nil {
+				cacheWarning(log, err)
+			}
+		}
+		return result
+	}
+
+	// Books are solved for the series win rate only.
+	if objective == nil {
+		if entry, ok := lookupOpeningBooks(tournamentInfo, gameState); ok {
+			result.WinRate, result.Line, result.ScoreOdds = entry.Value, entry.Line, entry.ScoreOdds
+			result.BlindSolution, result.IsBlind, result.Stats = SearchSimultaneousStep(tournamentInfo, gameState, SearchOptions{})
+			result.Source = "book"
+			logSearch(logStats(log, result.Stats), "recommend", result.Source, time.Since(start))
+			return result
+		}
+	}
+
+	// The searches below go over much of the same ground, so they share what they solve. The blind step is always
+	// solved for the series win rate, so it only shares when that is what the rest are searching for.
+	table := NewTranspositionTable()
+	value, line, stats := Search(tournamentInfo, gameState, isP1PickNext, SearchOptions{Objective: objective, Budget: searchBudget, Evaluator: PoolEvaluator, Table: table})
+	result.Line = line
+	if objective != nil {
+		result.ObjectiveValue = value
+	} else {
+		result.WinRate = value
+	}
+	// The line alone only tells how the series ends when none of it waits on a result, and a line the search budget
+	// cut short doesn't tell at all.
+	if tournamentInfo.Ruleset.DependsOnResults() && (objective != nil || searchBudget == 0) {
+		var scoreStats SearchStats
+		result.ScoreOdds, scoreStats = SearchScoreDistribution(tournamentInfo, gameState, SearchOptions{Objective: objective, Table: table})
+		stats = stats.Add(scoreStats)
+	} else if IsDraftComplete(tournamentInfo, result.Line) {
+		result.ScoreOdds = ScoreDistribution(tournamentInfo, result.Line)
+	}
+	if objective != nil {
+		result.WinRate = SeriesWin.Value(tournamentInfo, result.ScoreOdds)
+	}
+	blindOptions := SearchOptions{}
+	if objective == nil {
+		blindOptions.Table = table
+	}
+	var blindStats SearchStats
+	result.BlindSolution, result.IsBlind, blindStats = SearchSimultaneousStep(tournamentInfo, gameState, blindOptions)
+	result.Stats, result.Source = stats.Add(blindStats), "search"
+
+	registry.Observe("draftbot_search_nodes", float64(result.Stats.Nodes))
+	registry.Add("draftbot_search_nodes_total", float64(result.Stats.Nodes))
+	registry.Add("draftbot_search_transposition_hits_total", float64(result.Stats.TranspositionHits))
+	logSearch(logStats(log, result.Stats), "recommend", result.Source, time.Since(start))
+
+	if err == nil {
+		if err := searchCache.Put(key, result); err != nil {
+			cacheWarning(log, err)
+		}
+	}
+	return result
+}
+
+func lookupOpeningBooks(tournamentInfo TournamentInfo, gameState GameState) (BookEntry, bool) {
+	for _, v := range openingBooks {
+		if entry, ok := v.Lookup(tournamentInfo, gameState); ok {
+			return entry, true
+		}
+	}
+	return BookEntry{}, false
+}
+
+/**
+Every opening book in dir. Books for other odds are kept too, as they are only ever used where they match.
+*/
+func loadOpeningBooks(dir string) ([]OpeningBook, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var books []OpeningBook
+	for _, v := range paths {
+		data, err := os.ReadFile(v)
+		if err != nil {
+			return nil, err
+		}
+		var book OpeningBook
+		if err := json.Unmarshal(data, &book); err != nil {
+			return nil, fmt.Errorf("could not read opening book %s: %w", v, err)
+		}
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+/**
+Reviews the draft up to gameState within the search budget, or takes the review from the cache if it has been done
+before.
+*/
+func review(log *logging.Logger, tournamentInfo TournamentInfo, gameState GameState, threshold float64) DraftReview {
+	start := time.Now()
+	log = log.With("rounds", tournamentInfo.RoundCount, "ruleset", tournamentInfo.Ruleset.Name, "threshold", threshold, "budget", searchBudget)
+
+	key, err := cache.Key(tournamentInfo, gameState, fmt.Sprintf("review %v budget %s", threshold, searchBudget))
+	var result DraftReview
+	if err == nil && searchCache.Get(key, &result) {
+		logSearch(log, "review", "cache", time.Since(start))
+		return result
+	}
+	result, stats := SearchReview(tournamentInfo, gameState, threshold, SearchOptions{Budget: searchBudget, Evaluator: PoolEvaluator, Table: NewTranspositionTable()})
+	logSearch(logStats(log, stats), "review", "search", time.Since(start))
+	if err == nil {
+		if err := searchCache.Put(key, result); err != nil {
+			cacheWarning(log, err)
+		}
+	}
+	return result
+}
+
+/**
+Records a recommendation or review, which kind says, in the metrics and in log. source is where the result came from:
+search, cache or book. Only searches go into the duration histogram, so that cache hits don't hide how long searches
+take.
+*/
+func logSearch(log *logging.Logger, kind string, source string, duration time.Duration) {
+	registry.Add("draftbot_searches_total", 1, "kind", kind, "source", source)
+	if source == "search" {
+		registry.Observe("draftbot_search_duration_seconds", duration.Seconds(), "kind", kind)
+	}
+	log.Info(kind, "source", source, "durationMs", duration)
+}
+
+func logStats(log *logging.Logger, stats SearchStats) *logging.Logger {
+	return log.With(
+		"nodes", stats.Nodes,
+		"leaves", stats.Leaves,
+		"cutoffs", stats.Cutoffs,
+		"transpositionHits", stats.TranspositionHits,
+		"maxDepth", stats.MaxDepth,
+		"searchDurationMs", stats.Elapsed,
+	)
+}
+
+func cacheWarning(log *logging.Logger, err error) {
+	registry.Add("draftbot_errors_total", 1, "kind", "cache")
+	log.Warn("cannot cache result", "err", err)
+}
+
+/**
+The same recommendation as the draft page gives for the same query, as JSON.
+*/
+func recommendAPIHandler(c *gin.Context) {
+	tournamentInfo, gameState, isP1PickNext, err := parseInputs(c)
+	if err != nil {
+		inputWarning(c, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	objective, objectiveInputs, err := parseObjective(c)
+	opponent, opponentErr := parseOpponent(c)
+	err = firstError(err, opponentErr)
+	if err == nil && opponent != nil && objective != nil {
+		err = &inputError{Param: "opponent", Err: errors.New("opponents can only be played for the series win rate")}
+	}
+	// Playing an opponent can't prune, so without a budget to stop it only the final game is small enough to search.
+	if err == nil && opponent != nil && searchBudget == 0 && !ExploitIsTractable(tournamentInfo, gameState) {
+		err = &inputError{Param: "opponent", Err: errors.New("without a search budget, opponents can only be played from the final game on")}
+	}
+	if err != nil {
+		inputWarning(c, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recommend(requestLogger(c), tournamentInfo, gameState, isP1PickNext, objective, objectiveInputs, opponent))
+}
+
+func cacheStatsAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, searchCache.Stats())
+}
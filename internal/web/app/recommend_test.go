@@ -0,0 +1,79 @@
+package app
+
+import (
+	"encoding/json"
+	. "github.com/tmwilder/wh3-draftbot/internal/common"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// A Bo3 at the final game's counter ban, late enough to play a scouted opponent without pruning.
+const finalRoundDraft = "rounds=3&picks=SL+TZ&p1pick=TZ&p2pick=GC&picks=KH+OK&p1pick=OK&p2pick=KH&last-picks=GC+KI+NG&last-ban=KI"
+
+func TestRecommendAgainstOpponent(t *testing.T) {
+	r := testRouter(t)
+
+	var perfect, scouted recommendation
+	w := get(r, "/api/recommend?"+finalRoundDraft)
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &perfect) != nil {
+		t.Fatalf("Expected a recommendation but got %d %s", w.Code, w.Body.String())
+	}
+	// P2 is scouted to always play NG, so P1 gets to counter it.
+	w = get(r, "/api/recommend?"+finalRoundDraft+"&opponent=P2&opponent-picks=NG%3D1000&opponent-blend=1")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &scouted) != nil {
+		t.Fatalf("Expected a recommendation against P2 but got %d %s", w.Code, w.Body.String())
+	}
+	if scouted.WinRate < perfect.WinRate-epsilon {
+		t.Errorf("Expected playing a scouted P2 to be worth at least %f to P1 but got %f", perfect.WinRate, scouted.WinRate)
+	}
+	if scouted.Line.P3Round.CounterBan != NG && scouted.Line.P3Round.Matchup.P2 != NG {
+		t.Errorf("Expected the line to follow P2 to NG but got %+v", scouted.Line.P3Round)
+	}
+
+	// The same scouting of P1 can only help P2.
+	w = get(r, "/api/recommend?"+finalRoundDraft+"&opponent=P1&opponent-picks=NG%3D1000")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &scouted) != nil {
+		t.Fatalf("Expected a recommendation against P1 but got %d %s", w.Code, w.Body.String())
+	}
+	if scouted.WinRate > perfect.WinRate+epsilon {
+		t.Errorf("Expected playing a scouted P1 to be worth at most %f to P1 but got %f", perfect.WinRate, scouted.WinRate)
+	}
+}
+
+func TestRecommendAgainstOpponentEarly(t *testing.T) {
+	r := testRouter(t)
+
+	// The search can't prune against an opponent, so an empty Bo3 needs a budget.
+	w := get(r, "/api/recommend?rounds=3&opponent=P2&opponent-picks=NG%3D10")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an opponent without a search budget to be a bad request but got %d %s", w.Code, w.Body.String())
+	}
+
+	searchBudget = 200 * time.Millisecond
+	start := time.Now()
+	w = get(r, "/api/recommend?rounds=3&opponent=P2&opponent-picks=NG%3D10")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected a recommendation within the budget but got %d %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 10*searchBudget {
+		t.Errorf("Expected the search to keep to its %s budget but it took %s", searchBudget, elapsed)
+	}
+}
+
+func TestRecommendAgainstOpponentBadInput(t *testing.T) {
+	r := testRouter(t)
+
+	for name, query := range map[string]string{
+		"an unknown seat":       "&opponent=P3",
+		"a pick without weight": "&opponent=P2&opponent-picks=NG",
+		"a blend over 1":        "&opponent=P2&opponent-blend=2",
+		"an objective as well":  "&opponent=P2&objective=game-wins",
+	} {
+		w := get(r, "/api/recommend?"+finalRoundDraft+query)
+		var body map[string]string
+		if w.Code != http.StatusBadRequest || json.Unmarshal(w.Body.Bytes(), &body) != nil || body["error"] == "" {
+			t.Errorf("Expected %s to be a bad request but got %d %s", name, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/tmwilder/wh3-draftbot/internal/logging"
+	"github.com/tmwilder/wh3-draftbot/internal/metrics"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Set up when the app starts. Each request logs through a child of logger that carries its request ID.
+var logger, _ = logging.New(os.Stderr, "text")
+
+var registry = newRegistry()
+
+const requestIDHeader = "X-Request-ID"
+
+func newRegistry() *metrics.Registry {
+	registry := metrics.NewRegistry()
+	registry.Counter("draftbot_http_requests_total", "HTTP requests served, by method, route and status.")
+	registry.Histogram("draftbot_http_request_duration_seconds", "How long HTTP requests took to serve, by method and route.", metrics.LatencyBuckets)
+	registry.Counter("draftbot_searches_total", "Recommendations and reviews, by kind and by whether they were searched or came from the cache or an opening book.")
+	registry.Histogram("draftbot_search_duration_seconds", "How long recommendations and reviews that missed the cache took, by kind.", metrics.LatencyBuckets)
+	registry.Histogram("draftbot_search_nodes", "Positions each recommendation search visited.", metrics.NodeBuckets)
+	registry.Counter("draftbot_search_nodes_total", "Positions visited by every recommendation search.")
+	registry.Counter("draftbot_search_transposition_hits_total", "Positions recommendation searches took from another search rather than searching again.")
+	registry.Counter("draftbot_cache_hits_total", "Search cache hits, from memory or from disk.")
+	registry.Counter("draftbot_cache_misses_total", "Search cache misses.")
+	registry.Counter("draftbot_cache_evictions_total", "Search results dropped from memory to make room.")
+	registry.Gauge("draftbot_cache_entries", "Search results held in memory.")
+	registry.Counter("draftbot_errors_total", "Errors, by kind: input the server couldn't use, results it couldn't cache and server errors.")
+	return registry
+}
+
+/**
+Gives every request an ID, taken from its X-Request-ID header if it has a usable one, and records how it went in the
+metrics and, with accessLog, the log.
+*/
+func observeRequests(accessLog bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(requestIDHeader)
+		if !isUsableRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+		c.Set("logger", logger.With("requestId", requestID))
+
+		c.Next()
+
+		// Requests that match no route are grouped together so that probes for random paths don't make new series.
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		duration := time.Since(start)
+		registry.Add("draftbot_http_requests_total", 1, "method", c.Request.Method, "route", route, "status", strconv.Itoa(status))
+		registry.Observe("draftbot_http_request_duration_seconds", duration.Seconds(), "method", c.Request.Method, "route", route)
+		if status >= http.StatusInternalServerError {
+			registry.Add("draftbot_errors_total", 1, "kind", "server")
+		}
+		if accessLog {
+			requestLogger(c).Info("request",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"query", c.Request.URL.RawQuery,
+				"route", route,
+				"status", status,
+				"durationMs", duration,
+				"bytes", c.Writer.Size(),
+				"clientIp", c.ClientIP(),
+			)
+		}
+	}
+}
+
+/**
+The logger for the request c is serving, which adds its request ID to every line.
+*/
+func requestLogger(c *gin.Context) *logging.Logger {
+	if v, ok := c.Get("logger"); ok {
+		return v.(*logging.Logger)
+	}
+	return logger
+}
+
+/**
+Logs input the request had that the server couldn't use and went on without.
+*/
+func inputWarning(c *gin.Context, err error) {
+	registry.Add("draftbot_errors_total", 1, "kind", "input")
+	requestLogger(c).Warn("cannot use input", "err", err)
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+/**
+IDs passed in by a proxy are kept as long as they are short and plain enough to put in a log line and a header.
+*/
+func isUsableRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 64 {
+		return false
+	}
+	for _, v := range requestID {
+		if !(v == '-' || v == '_' || v == '.' || (v >= '0' && v <= '9') || (v >= 'a' && v <= 'z') || (v >= 'A' && v <= 'Z')) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+Every metric in Prometheus' text format, for Prometheus to scrape.
+*/
+func metricsHandler(c *gin.Context) {
+	stats := searchCache.Stats()
+	registry.Set("draftbot_cache_hits_total", float64(stats.Hits), "tier", "memory")
+	registry.Set("draftbot_cache_hits_total", float64(stats.DiskHits), "tier", "disk")
+	registry.Set("draftbot_cache_misses_total", float64(stats.Misses))
+	registry.Set("draftbot_cache_evictions_total", float64(stats.Evictions))
+	registry.Set("draftbot_cache_entries", float64(stats.Entries))
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	registry.WriteTo(c.Writer)
+}
@@ -0,0 +1,12 @@
+package web
+
+import (
+	"embed"
+)
+
+/**
+Files are the page templates under template/ and the stylesheet and other assets under static/, built into the binary
+so the server runs from anywhere and needs no internet connection.
+*/
+//go:embed template static
+var Files embed.FS